@@ -1,7 +1,12 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/jbrekelmans/kube-compose/pkg/config"
 	"github.com/urfave/cli"
@@ -11,8 +16,14 @@ import (
 )
 
 const (
+	envFileFlagName       = "env-file"
+	ciJobIDFlagName       = "ci-job-id"
 	environmentIDFlagName = "env-id"
+	fileFlagName          = "file"
+	indexFlagName         = "index"
 	namespaceFlagName     = "namespace"
+	timeoutFlagName       = "timeout"
+	ttlFlagName           = "ttl"
 )
 
 func GlobalFlags() []cli.Flag {
@@ -22,6 +33,14 @@ func GlobalFlags() []cli.Flag {
 			EnvVar: "KUBECOMPOSE_ENVID",
 			Usage:  "used to isolate environments deployed to a shared namespace, by (1) using this value as a suffix of pod and service names and (2) using this value to isolate selectors",
 		},
+		cli.StringFlag{
+			Name:  envFileFlagName,
+			Usage: "specify an alternate env file used for variable substitution in docker compose files (default: .env in the directory of the first docker compose file)",
+		},
+		cli.StringSliceFlag{
+			Name:  fileFlagName + ", f",
+			Usage: "specify an alternate docker compose file (default: docker-compose.yml), can be specified multiple times to merge files (default from environment variable COMPOSE_FILE)",
+		},
 		cli.StringFlag{
 			Name:   namespaceFlagName + ", n",
 			EnvVar: "KUBECOMPOSE_NAMESPACE",
@@ -30,25 +49,72 @@ func GlobalFlags() []cli.Flag {
 	}
 }
 
-func newConfigFromEnv() (*config.Config, error) {
-	cfg, err := config.New()
+// getFileNamesFromCli returns the docker compose files set via the file flag, falling back to the COMPOSE_FILE environment variable.
+// The environment variable is split with the same separator as docker-compose: https://docs.docker.com/compose/reference/envvars/
+func getFileNamesFromCli(c *cli.Context) []string {
+	fileNames := c.GlobalStringSlice(fileFlagName)
+	if len(fileNames) > 0 {
+		return fileNames
+	}
+	composeFile, ok := os.LookupEnv("COMPOSE_FILE")
+	if !ok || len(composeFile) == 0 {
+		return nil
+	}
+	separator, ok := os.LookupEnv("COMPOSE_PATH_SEPARATOR")
+	if !ok || len(separator) == 0 {
+		separator = string(os.PathListSeparator)
+	}
+	return strings.Split(composeFile, separator)
+}
+
+// newConfigFromCli loads the docker compose files, without loading the Kubernetes configuration.
+func newConfigFromCli(c *cli.Context) (*config.Config, error) {
+	return config.New(getFileNamesFromCli(c), c.GlobalString(envFileFlagName))
+}
+
+func newConfigFromEnv(c *cli.Context) (*config.Config, error) {
+	cfg, err := newConfigFromCli(c)
+	if err != nil {
+		return nil, err
+	}
+	err = loadKubeConfig(cfg)
 	if err != nil {
 		return nil, err
 	}
+	return cfg, nil
+}
+
+func newKubeClientConfig() clientcmd.ClientConfig {
 	loader := clientcmd.NewDefaultClientConfigLoadingRules()
 	overrides := clientcmd.ConfigOverrides{}
-	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loader, &overrides)
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loader, &overrides)
+}
+
+// loadKubeConfig sets the Kubernetes configuration and namespace of cfg from the default kubeconfig loading rules.
+func loadKubeConfig(cfg *config.Config) error {
+	clientConfig := newKubeClientConfig()
 	kubeConfig, err := clientConfig.ClientConfig()
 	if err != nil {
-		return nil, err
+		return err
 	}
 	namespace, _, err := clientConfig.Namespace()
 	if err != nil {
-		return nil, err
+		return err
 	}
 	cfg.KubeConfig = kubeConfig
 	cfg.Namespace = namespace
-	return cfg, nil
+	return nil
+}
+
+// loadNamespace sets only the namespace of cfg from the default kubeconfig loading rules, so that commands that do not contact the
+// Kubernetes API server (e.g. up --dry-run) do not require a kubeconfig with credentials.
+func loadNamespace(cfg *config.Config) error {
+	namespace, _, err := newKubeClientConfig().Namespace()
+	if err != nil {
+		return err
+	}
+	cfg.Namespace = namespace
+	return nil
 }
 
 func updateConfigFromCli(cfg *config.Config, c *cli.Context) error {
@@ -60,7 +126,11 @@ func updateConfigFromCli(cfg *config.Config, c *cli.Context) error {
 		return fmt.Errorf("environment id must not be empty")
 	}
 	cfg.EnvironmentID = environmentID
+	return updateNamespaceFromCli(cfg, c)
+}
 
+// updateNamespaceFromCli overrides the namespace of cfg if it is set via the namespace flag.
+func updateNamespaceFromCli(cfg *config.Config, c *cli.Context) error {
 	namespace := c.GlobalString(namespaceFlagName)
 	if len(namespace) > 0 || c.GlobalIsSet(namespaceFlagName) {
 		if len(namespace) == 0 {
@@ -70,3 +140,75 @@ func updateConfigFromCli(cfg *config.Config, c *cli.Context) error {
 	}
 	return nil
 }
+
+// getServiceAndCommandFromCli parses the arguments SERVICE [--] [COMMAND [ARGS...]] of commands like exec and run.
+func getServiceAndCommandFromCli(c *cli.Context) (string, []string, error) {
+	args := c.Args()
+	if len(args) == 0 {
+		return "", nil, fmt.Errorf("a service is required")
+	}
+	command := args[1:]
+	if len(command) > 0 && command[0] == "--" {
+		command = command[1:]
+	}
+	return args[0], command, nil
+}
+
+// exitCodeToError converts a non-zero exit code of a command run in a pod into an error that causes kube-compose to exit with the
+// same code.
+func exitCodeToError(exitCode int, err error) error {
+	if err != nil {
+		return err
+	}
+	if exitCode != 0 {
+		return cli.NewExitError("", exitCode)
+	}
+	return nil
+}
+
+func newTimeoutFlag() cli.Flag {
+	return cli.DurationFlag{
+		Name:  timeoutFlagName,
+		Usage: "abort if the command takes longer than this duration (e.g. 10m), zero means no timeout",
+	}
+}
+
+// newTTLFlag and newCIJobIDFlag are the flags of commands that create resources, which are annotated so that gc can delete them.
+func newTTLFlag() cli.Flag {
+	return cli.DurationFlag{
+		Name:  ttlFlagName,
+		Usage: "the duration (e.g. 2h) after which the environment expires and is deleted by gc, zero means the environment does not expire",
+	}
+}
+
+func newCIJobIDFlag() cli.Flag {
+	return cli.StringFlag{
+		Name:   ciJobIDFlagName,
+		EnvVar: "KUBECOMPOSE_CI_JOB_ID",
+		Usage:  "the id of the CI job that runs the command, so that gc can delete the environment once the job is gone",
+	}
+}
+
+// newContextFromCli returns a context that is cancelled when SIGINT or SIGTERM is received, or when the timeout set via the timeout
+// flag expires.
+func newContextFromCli(c *cli.Context) (context.Context, context.CancelFunc) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout := c.Duration(timeoutFlagName); timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			fmt.Printf("received signal %s, aborting\n", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+	return ctx, cancel
+}
@@ -10,8 +10,11 @@ func NewDownCommand() cli.Command {
 	return cli.Command{
 		Name:  "down",
 		Usage: "deletes pods and services",
+		Flags: []cli.Flag{
+			newTimeoutFlag(),
+		},
 		Action: func(c *cli.Context) error {
-			cfg, err := newConfigFromEnv()
+			cfg, err := newConfigFromEnv(c)
 			if err != nil {
 				return err
 			}
@@ -19,7 +22,9 @@ func NewDownCommand() cli.Command {
 			if err != nil {
 				return err
 			}
-			return down.Run(cfg)
+			ctx, cancel := newContextFromCli(c)
+			defer cancel()
+			return down.Run(ctx, cfg)
 		},
 	}
 }
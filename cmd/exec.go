@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"github.com/urfave/cli"
+
+	"github.com/jbrekelmans/kube-compose/pkg/exec"
+)
+
+const (
+	stdinFlagName = "stdin"
+	ttyFlagName   = "tty"
+)
+
+func NewExecCommand() cli.Command {
+	return cli.Command{
+		Name:      "exec",
+		Usage:     "executes a command in the running pod of a service",
+		ArgsUsage: "SERVICE [--] COMMAND [ARGS...]",
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  stdinFlagName + ", i",
+				Usage: "pass stdin to the command",
+			},
+			cli.BoolFlag{
+				Name:  ttyFlagName + ", t",
+				Usage: "allocate a TTY",
+			},
+			cli.IntFlag{
+				Name:  indexFlagName,
+				Usage: "the index of the pod if the service has multiple pods (e.g. replicas), as listed by ps",
+				Value: 1,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			cfg, err := newConfigFromEnv(c)
+			if err != nil {
+				return err
+			}
+			err = updateConfigFromCli(cfg, c)
+			if err != nil {
+				return err
+			}
+			service, command, err := getServiceAndCommandFromCli(c)
+			if err != nil {
+				return err
+			}
+			cfg.Services = []string{service}
+			opts := &exec.Options{
+				Command: command,
+				Index:   c.Int(indexFlagName),
+				Service: service,
+				Stdin:   c.Bool(stdinFlagName),
+				TTY:     c.Bool(ttyFlagName),
+			}
+			return exitCodeToError(exec.Run(cfg, opts))
+		},
+	}
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"github.com/urfave/cli"
+
+	"github.com/jbrekelmans/kube-compose/pkg/config"
+	"github.com/jbrekelmans/kube-compose/pkg/gc"
+)
+
+const (
+	ciJobLabelFlagName     = "ci-job-label"
+	ciJobNamespaceFlagName = "ci-job-namespace"
+)
+
+func NewGCCommand() cli.Command {
+	return cli.Command{
+		Name:  "gc",
+		Usage: "deletes the environments in the namespace that expired, or whose CI job is gone",
+		Flags: []cli.Flag{
+			newTimeoutFlag(),
+			cli.BoolFlag{
+				Name:  dryRunFlagName,
+				Usage: "print the environments that would be deleted instead of deleting them",
+			},
+			cli.StringFlag{
+				Name:  ciJobLabelFlagName,
+				Usage: "the label of the pods of CI jobs that holds the job id, environments whose --" + ciJobIDFlagName + " has no such pod are deleted",
+			},
+			cli.StringFlag{
+				Name:  ciJobNamespaceFlagName,
+				Usage: "the namespace of the pods of CI jobs (default: the target namespace)",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			// gc does not need docker compose files, because environments are found by their labels.
+			cfg := &config.Config{
+				EnvironmentLabel: config.DefaultEnvironmentLabel,
+			}
+			err := loadKubeConfig(cfg)
+			if err != nil {
+				return err
+			}
+			err = updateNamespaceFromCli(cfg, c)
+			if err != nil {
+				return err
+			}
+			opts := &gc.Options{
+				CIJobLabel:     c.String(ciJobLabelFlagName),
+				CIJobNamespace: c.String(ciJobNamespaceFlagName),
+				DryRun:         c.Bool(dryRunFlagName),
+			}
+			ctx, cancel := newContextFromCli(c)
+			defer cancel()
+			return gc.Run(ctx, cfg, opts)
+		},
+	}
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"github.com/urfave/cli"
+
+	"github.com/jbrekelmans/kube-compose/pkg/logs"
+)
+
+const (
+	followFlagName     = "follow"
+	sinceFlagName      = "since"
+	tailFlagName       = "tail"
+	timestampsFlagName = "timestamps"
+)
+
+func NewLogsCommand() cli.Command {
+	return cli.Command{
+		Name:  "logs",
+		Usage: "prints the logs of the pods of all (or the specified) services",
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  followFlagName + ", f",
+				Usage: "follow log output",
+			},
+			cli.IntFlag{
+				Name:  indexFlagName,
+				Usage: "only print the logs of the pod with this index of each service (e.g. a replica), as listed by ps (default: all pods)",
+			},
+			cli.StringFlag{
+				Name:  sinceFlagName,
+				Usage: "only show logs since a relative duration (e.g. 10m) or an RFC3339 timestamp",
+			},
+			cli.StringFlag{
+				Name:  tailFlagName,
+				Usage: "number of lines to show from the end of the logs of each pod",
+				Value: "all",
+			},
+			cli.BoolFlag{
+				Name:  timestampsFlagName + ", t",
+				Usage: "show timestamps",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			cfg, err := newConfigFromEnv(c)
+			if err != nil {
+				return err
+			}
+			err = updateConfigFromCli(cfg, c)
+			if err != nil {
+				return err
+			}
+			opts := &logs.Options{
+				Follow:     c.Bool(followFlagName),
+				Index:      c.Int(indexFlagName),
+				Since:      c.String(sinceFlagName),
+				Tail:       c.String(tailFlagName),
+				Timestamps: c.Bool(timestampsFlagName),
+			}
+			return logs.Run(cfg, opts)
+		},
+	}
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"github.com/urfave/cli"
+
+	"github.com/jbrekelmans/kube-compose/pkg/ps"
+)
+
+const outputFlagName = "output"
+
+func NewPsCommand() cli.Command {
+	return cli.Command{
+		Name:  "ps",
+		Usage: "lists the pod and service state of each docker compose service",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  outputFlagName + ", o",
+				Usage: "the output format, one of table, json and yaml",
+				Value: ps.OutputFormatTable,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			cfg, err := newConfigFromEnv(c)
+			if err != nil {
+				return err
+			}
+			err = updateConfigFromCli(cfg, c)
+			if err != nil {
+				return err
+			}
+			return ps.Run(cfg, c.String(outputFlagName))
+		},
+	}
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"github.com/urfave/cli"
+
+	"github.com/jbrekelmans/kube-compose/pkg/up"
+)
+
+const keepFlagName = "keep"
+
+func NewRunCommand() cli.Command {
+	return cli.Command{
+		Name:      "run",
+		Usage:     "runs a one-off command in a new pod of a service, and deletes the pod afterwards",
+		ArgsUsage: "SERVICE [--] [COMMAND [ARGS...]]",
+		Flags: []cli.Flag{
+			newTimeoutFlag(),
+			newTTLFlag(),
+			newCIJobIDFlag(),
+			cli.BoolFlag{
+				Name:  keepFlagName,
+				Usage: "do not delete the pod after the command terminated",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			cfg, err := newConfigFromEnv(c)
+			if err != nil {
+				return err
+			}
+			err = updateConfigFromCli(cfg, c)
+			if err != nil {
+				return err
+			}
+			service, command, err := getServiceAndCommandFromCli(c)
+			if err != nil {
+				return err
+			}
+			cfg.Services = []string{service}
+			opts := &up.RunOptions{
+				CIJobID: c.String(ciJobIDFlagName),
+				Command: command,
+				Keep:    c.Bool(keepFlagName),
+				Service: service,
+				TTL:     c.Duration(ttlFlagName),
+			}
+			ctx, cancel := newContextFromCli(c)
+			defer cancel()
+			return exitCodeToError(up.RunOneOff(ctx, cfg, opts))
+		},
+	}
+}
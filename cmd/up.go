@@ -1,17 +1,110 @@
 package cmd
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jbrekelmans/kube-compose/pkg/config"
 	"github.com/urfave/cli"
 
 	"github.com/jbrekelmans/kube-compose/pkg/up"
 )
 
+const (
+	abortOnContainerExitFlagName = "abort-on-container-exit"
+	dryRunFlagName               = "dry-run"
+	exitCodeFromFlagName         = "exit-code-from"
+	maxRestartsFlagName          = "max-restarts"
+	outputDirFlagName            = "output-dir"
+	scaleFlagName                = "scale"
+)
+
+// updateReplicasFromCli sets the number of pods of services specified via the scale flag (e.g. --scale web=3).
+func updateReplicasFromCli(cfg *config.Config, c *cli.Context) error {
+	for _, scale := range c.StringSlice(scaleFlagName) {
+		i := strings.IndexRune(scale, '=')
+		if i < 0 {
+			return fmt.Errorf("invalid --%s %#v, expected SERVICE=NUM", scaleFlagName, scale)
+		}
+		name := scale[:i]
+		service, ok := cfg.CanonicalComposeFile.Services[name]
+		if !ok {
+			return fmt.Errorf("no service named %#v exists", name)
+		}
+		replicas, err := strconv.Atoi(scale[i+1:])
+		if err != nil || replicas < 1 {
+			return fmt.Errorf("invalid --%s %#v, the number of replicas must be a positive integer", scaleFlagName, scale)
+		}
+		service.Replicas = replicas
+	}
+	return nil
+}
+
 func NewUpCommand() cli.Command {
 	return cli.Command{
 		Name:  "up",
 		Usage: "creates pods and services in an order that respects depends_on in the docker compose file",
+		Flags: []cli.Flag{
+			newTimeoutFlag(),
+			cli.BoolFlag{
+				Name:  abortOnContainerExitFlagName,
+				Usage: "wait for containers to exit instead of returning once all pods are ready, and abort if a container exits with a non-zero code",
+			},
+			cli.StringFlag{
+				Name:  exitCodeFromFlagName,
+				Usage: "stream the logs of the container of this service and exit with its exit code, implies --" + abortOnContainerExitFlagName,
+			},
+			cli.BoolFlag{
+				Name:  dryRunFlagName,
+				Usage: "print the Kubernetes resources that would be created instead of creating them, without contacting the cluster",
+			},
+			cli.StringFlag{
+				Name:  outputFlagName + ", o",
+				Usage: "the output format of --dry-run, one of yaml and json",
+				Value: up.RenderFormatYAML,
+			},
+			cli.StringFlag{
+				Name:  outputDirFlagName,
+				Usage: "with --dry-run, write one file per resource to this directory instead of printing to stdout",
+			},
+			cli.IntFlag{
+				Name:  maxRestartsFlagName,
+				Usage: "abort if a container terminates after it was restarted this many times according to the restart policy of its service",
+				Value: 3,
+			},
+			cli.StringSliceFlag{
+				Name:  scaleFlagName,
+				Usage: "scale SERVICE to NUM pods (SERVICE=NUM), overrides scale and deploy.replicas, can be specified multiple times",
+			},
+			newTTLFlag(),
+			newCIJobIDFlag(),
+		},
 		Action: func(c *cli.Context) error {
-			cfg, err := newConfigFromEnv()
+			if c.Bool(dryRunFlagName) {
+				cfg, err := newConfigFromCli(c)
+				if err != nil {
+					return err
+				}
+				err = loadNamespace(cfg)
+				if err != nil {
+					return err
+				}
+				err = updateConfigFromCli(cfg, c)
+				if err != nil {
+					return err
+				}
+				err = updateReplicasFromCli(cfg, c)
+				if err != nil {
+					return err
+				}
+				opts := &up.RenderOptions{
+					OutputDir:    c.String(outputDirFlagName),
+					OutputFormat: c.String(outputFlagName),
+				}
+				return up.Render(cfg, opts)
+			}
+			cfg, err := newConfigFromEnv(c)
 			if err != nil {
 				return err
 			}
@@ -19,7 +112,20 @@ func NewUpCommand() cli.Command {
 			if err != nil {
 				return err
 			}
-			return up.Run(cfg)
+			err = updateReplicasFromCli(cfg, c)
+			if err != nil {
+				return err
+			}
+			opts := &up.Options{
+				AbortOnContainerExit: c.Bool(abortOnContainerExitFlagName),
+				ExitCodeFrom:         c.String(exitCodeFromFlagName),
+				MaxRestarts:          c.Int(maxRestartsFlagName),
+				TTL:                  c.Duration(ttlFlagName),
+				CIJobID:              c.String(ciJobIDFlagName),
+			}
+			ctx, cancel := newContextFromCli(c)
+			defer cancel()
+			return exitCodeToError(up.Run(ctx, cfg, opts))
 		},
 	}
 }
@@ -17,6 +17,11 @@ func main() {
 	app.Version = "3.0.2"
 	app.Commands = []cli.Command{
 		cmd.NewDownCommand(),
+		cmd.NewExecCommand(),
+		cmd.NewGCCommand(),
+		cmd.NewLogsCommand(),
+		cmd.NewPsCommand(),
+		cmd.NewRunCommand(),
 		cmd.NewUpCommand(),
 	}
 	err = app.Run(os.Args)
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ServiceBuild is the parsed form of the build field of a docker compose service.
+// https://docs.docker.com/compose/compose-file/compose-file-v2/#build
+type ServiceBuild struct {
+	Args       map[string]*string // a nil value means the Dockerfile's default value of the build argument is used
+	CacheFrom  []string
+	Context    string // an absolute path
+	Dockerfile string // relative to Context, defaults to "Dockerfile"
+	Target     string
+}
+
+// isRemoteBuildContext returns true if and only if context is a git repository or URL, as per
+// https://docs.docker.com/engine/reference/commandline/build/#extended-description
+func isRemoteBuildContext(context string) bool {
+	return strings.Contains(context, "://") || strings.HasPrefix(context, "git@") || strings.HasPrefix(context, "github.com/")
+}
+
+func parseBuild(buildYAML *ServiceBuildYAML, valueGetter ValueGetter) (*ServiceBuild, error) {
+	if buildYAML == nil {
+		return nil, nil
+	}
+	if len(buildYAML.Context) == 0 {
+		return nil, fmt.Errorf("field \"context\" of build must not be empty")
+	}
+	if isRemoteBuildContext(buildYAML.Context) {
+		return nil, fmt.Errorf("build context %s is not supported, only local directories are supported", buildYAML.Context)
+	}
+	build := &ServiceBuild{
+		Args:       make(map[string]*string, len(buildYAML.Args.Values)),
+		CacheFrom:  buildYAML.CacheFrom,
+		Context:    buildYAML.Context,
+		Dockerfile: buildYAML.Dockerfile,
+		Target:     buildYAML.Target,
+	}
+	if len(build.Dockerfile) == 0 {
+		build.Dockerfile = "Dockerfile"
+	}
+
+	// Build arguments without a value are resolved from the environment, like docker-compose.
+	for _, pair := range buildYAML.Args.Values {
+		if len(pair.Name) == 0 {
+			return nil, fmt.Errorf("invalid build argument: %s", pair.Name)
+		}
+		var value string
+		var ok bool
+		if pair.Value == nil {
+			value, ok = valueGetter(pair.Name)
+		} else {
+			value, ok = pair.Value.stringValue()
+		}
+		if ok {
+			build.Args[pair.Name] = &value
+		} else {
+			build.Args[pair.Name] = nil
+		}
+	}
+	return build, nil
+}
@@ -4,7 +4,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"strconv"
+	"path/filepath"
+	"strings"
 
 	version "github.com/hashicorp/go-version"
 	"github.com/pkg/errors"
@@ -16,28 +17,50 @@ import (
 
 var (
 	v1   = version.Must(version.NewVersion("1"))
+	v2   = version.Must(version.NewVersion("2"))
 	v2_1 = version.Must(version.NewVersion("2.1"))
+	v2_2 = version.Must(version.NewVersion("2.2"))
+	v2_3 = version.Must(version.NewVersion("2.3"))
+	v3   = version.Must(version.NewVersion("3"))
 	v3_1 = version.Must(version.NewVersion("3.1"))
+	v3_2 = version.Must(version.NewVersion("3.2"))
 	v3_3 = version.Must(version.NewVersion("3.3"))
+	v3_4 = version.Must(version.NewVersion("3.4"))
+	v3_7 = version.Must(version.NewVersion("3.7"))
+	v3_9 = version.Must(version.NewVersion("3.9"))
+	v4   = version.Must(version.NewVersion("4"))
 )
 
 // TODO https://github.com/jbrekelmans/kube-compose/issues/11 remove this type
 type genericMap map[interface{}]interface{}
 
 type CanonicalComposeFile struct {
+	Networks map[string]*Network
 	Services map[string]*Service
 	Version  *version.Version
+	Volumes  map[string]*Volume
 }
 
 type Service struct {
+	Build               *ServiceBuild
+	Command             []string
 	DependsOn           map[*Service]ServiceHealthiness
 	Entrypoint          []string
 	Environment         map[string]string
 	Healthcheck         *Healthcheck
 	HealthcheckDisabled bool
 	Image               string
+	Kind                string // one of KindPod, KindJob, KindDeployment and KindStatefulSet.
+	Networks            map[string]*ServiceNetwork
+	Pod                 PodConfig // the x-kube-compose of the service merged with the top-level x-kube-compose.
 	Ports               []PortBinding
+	Probe               *Probe // overrides the command of probes derived from the healthcheck, see x-kube-compose.probe.
+	Replicas            int    // the number of pods, see scale and deploy.replicas.
+	ReplicasReady       string // one of ReplicasReadyAll and ReplicasReadyOne.
+	Restart             string // one of RestartNo, RestartAlways, RestartOnFailure and RestartUnlessStopped.
+	RestartMaxRetries   int    // the maximum number of restarts of restart: on-failure:max-retries, or 0 if unlimited.
 	ServiceName         string
+	Volumes             []ServiceVolume
 	WorkingDir          string
 
 	// helpers for ensureNoDependsOnCycle
@@ -49,53 +72,74 @@ type PushImagesConfig struct {
 	DockerRegistry string `mapdecode:"docker_registry"`
 }
 
+// DefaultEnvironmentLabel is the label that holds the environment id of all Kubernetes resources of an environment.
+const DefaultEnvironmentLabel = "env"
+
 type Config struct {
 	CanonicalComposeFile CanonicalComposeFile
+	DNS                  DNSConfig
 	EnvironmentID        string // All Kubernetes resources are named with "-"+EnvironmentID as a suffix, and have an additional label "env="+EnvironmentID so that namespaces can be shared.
 	EnvironmentLabel     string
 	KubeConfig           *rest.Config
 	Namespace            string
+	NetworkPolicies      bool      // whether to create a NetworkPolicy per docker compose network, so that networks are isolated like in docker.
+	Pod                  PodConfig // the defaults of Service.Pod.
+	Probes               ProbesConfig
 	PushImages           *PushImagesConfig
+	ReplicasReady        string // the default of Service.ReplicasReady.
+	ResolvedComposeFile  []byte // the docker compose files in YAML, after merging, substituting variables and resolving extends.
 	Services             []string
 }
 
-func New() (*Config, error) {
-	fileName := "docker-compose.yml"
-	data, err := ioutil.ReadFile(fileName)
-	if err != nil {
-		if os.IsNotExist(err) {
-			fileName = "docker-compose.yaml"
-			data, err = ioutil.ReadFile(fileName)
-		}
+// New loads the docker compose files fileNames, merging them in the same way as docker-compose's -f flag when specified multiple
+// times. If fileNames is empty then docker-compose.yml (or docker-compose.yaml) and its optional override file are loaded.
+// Variables are substituted with values from the environment and the env file envFileName. If envFileName is empty then the optional
+// file .env in the directory of the first docker compose file is used.
+func New(fileNames []string, envFileName string) (*Config, error) {
+	if len(fileNames) == 0 {
+		var err error
+		fileNames, err = getDefaultFileNames()
 		if err != nil {
 			return nil, err
 		}
 	}
-
-	var dataMap genericMap
-	err = yaml.Unmarshal(data, &dataMap)
+	envFileRequired := true
+	if len(envFileName) == 0 {
+		envFileName = filepath.Join(filepath.Dir(fileNames[0]), DefaultEnvFileName)
+		envFileRequired = false
+	}
+	valueGetter, err := newValueGetterFromEnvFile(envFileName, envFileRequired)
 	if err != nil {
 		return nil, err
 	}
-
+	var dataMap genericMap
 	var ver *version.Version
-	verRaw, hasVer := dataMap["version"]
-	if !hasVer {
-		ver = v1
-	} else if verStr, ok := verRaw.(string); ok {
-		ver, err = version.NewVersion(verStr)
+	for i, fileName := range fileNames {
+		dataMapFile, verFile, err := loadFile(fileName, valueGetter)
 		if err != nil {
-			return nil, fmt.Errorf("file %#v has an invalid version: %#v", fileName, verStr)
+			return nil, err
+		}
+		// Each file is validated before merging, because merging can rewrite fields (e.g. depends_on lists become mappings).
+		err = validateVersion(verFile, dataMapFile)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("error while parsing docker compose %#v", fileName))
+		}
+		if i == 0 {
+			dataMap = dataMapFile
+			ver = verFile
+			continue
+		}
+		if !verFile.Equal(ver) {
+			return nil, fmt.Errorf("version mismatch: file %#v specifies version %s but extension file %#v uses version %s", fileNames[0], ver, fileName, verFile)
+		}
+		dataMap, err = mergeComposeFiles(dataMap, dataMapFile)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("error while merging docker compose %#v", fileName))
 		}
-	} else {
-		return nil, fmt.Errorf("file %#v has a version that is not a string", fileName)
 	}
+	fileName := strings.Join(fileNames, ", ")
 
-	// Substitute variables with environment variables.
-	err = InterpolateConfig(fileName, dataMap, func(name string) (string, bool) {
-		val, found := os.LookupEnv(name)
-		return val, found
-	}, ver)
+	err = resolveExtends(fileNames[0], dataMap, valueGetter)
 	if err != nil {
 		return nil, err
 	}
@@ -107,11 +151,17 @@ func New() (*Config, error) {
 	}
 
 	var custom struct {
-		Custom struct {
-			PushImages *PushImagesConfig `mapdecode:"push_images"`
-		} `mapdecode:"x-kube-compose"`
+		PodConfig `mapdecode:",squash"`
+
+		DNS             *DNSConfig                              `mapdecode:"dns"`
+		NetworkPolicies bool                                    `mapdecode:"network_policies"`
+		Probes          *ProbesConfig                           `mapdecode:"probes"`
+		PushImages      *PushImagesConfig                       `mapdecode:"push_images"`
+		ReplicasReady   string                                  `mapdecode:"replicas_ready"`
+		Volumes         map[string]*PersistentVolumeClaimConfig `mapdecode:"volumes"`
 	}
-	err = mapdecode.Decode(&custom, dataMap, mapdecode.IgnoreUnused(true))
+	// Unlike the rest of the docker compose file, unknown keys of x-kube-compose are rejected.
+	err = mapdecode.Decode(&custom, dataMap["x-kube-compose"])
 	if err != nil {
 		return nil, errors.Wrap(err, fmt.Sprintf("error while parsing x-kube-compose of %#v", fileName))
 	}
@@ -120,12 +170,28 @@ func New() (*Config, error) {
 		CanonicalComposeFile: CanonicalComposeFile{
 			Version: ver,
 		},
-		EnvironmentLabel: "env",
+		EnvironmentLabel: DefaultEnvironmentLabel,
+	}
+	cfg.ResolvedComposeFile, err = yaml.Marshal(dataMap)
+	if err != nil {
+		return nil, err
+	}
+	cfg.CanonicalComposeFile.Volumes, err = parseVolumes(composeFile.Volumes, custom.Volumes)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("error while parsing docker compose %#v", fileName))
 	}
-	err = parseCompose2_1(&composeFile, &cfg.CanonicalComposeFile)
+	cfg.CanonicalComposeFile.Networks, err = parseNetworks(composeFile.Networks)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("error while parsing docker compose %#v", fileName))
+	}
+	err = parseCompose2_1(&composeFile, &cfg.CanonicalComposeFile, valueGetter)
 	if err != nil {
 		return nil, err
 	}
+	err = ensureVolumesNotShared(cfg.CanonicalComposeFile.Services, cfg.CanonicalComposeFile.Volumes)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("error while parsing docker compose %#v", fileName))
+	}
 
 	for name := range cfg.CanonicalComposeFile.Services {
 		if errors := validation.IsDNS1123Subdomain(name); len(errors) > 0 {
@@ -133,13 +199,174 @@ func New() (*Config, error) {
 		}
 	}
 
-	if custom.Custom.PushImages != nil {
-		cfg.PushImages = custom.Custom.PushImages
+	cfg.DNS, err = parseDNSConfig(custom.DNS, cfg.CanonicalComposeFile.Services)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("error while parsing x-kube-compose of %#v", fileName))
+	}
+
+	cfg.NetworkPolicies = custom.NetworkPolicies
+
+	cfg.Pod = custom.PodConfig
+	err = parsePodConfig(&cfg.Pod, cfg.EnvironmentLabel)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("error while parsing x-kube-compose of %#v", fileName))
+	}
+	for name, service := range cfg.CanonicalComposeFile.Services {
+		err = parsePodConfig(&service.Pod, cfg.EnvironmentLabel)
+		if err != nil {
+			return nil, errors.Wrap(fmt.Errorf("service %s %v", name, err), fmt.Sprintf("error while parsing docker compose %#v", fileName))
+		}
+		service.Pod = mergePodConfigs(&cfg.Pod, &service.Pod)
+	}
+
+	cfg.ReplicasReady, err = parseReplicasReady(custom.ReplicasReady, ReplicasReadyAll)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("error while parsing x-kube-compose of %#v", fileName))
+	}
+	for name, service := range cfg.CanonicalComposeFile.Services {
+		service.ReplicasReady, err = parseReplicasReady(service.ReplicasReady, cfg.ReplicasReady)
+		if err != nil {
+			return nil, errors.Wrap(fmt.Errorf("service %s %v", name, err), fmt.Sprintf("error while parsing docker compose %#v", fileName))
+		}
+	}
+
+	cfg.Probes, err = parseProbesConfig(custom.Probes)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("error while parsing x-kube-compose of %#v", fileName))
+	}
+
+	if custom.PushImages != nil {
+		cfg.PushImages = custom.PushImages
 	}
 
 	return cfg, nil
 }
 
+// getDefaultFileNames returns the docker compose files that are loaded when no files are specified explicitly, as per
+// https://docs.docker.com/compose/extends/#understanding-multiple-compose-files
+func getDefaultFileNames() ([]string, error) {
+	fileName, err := findFirstExistingFile("docker-compose.yml", "docker-compose.yaml")
+	if err != nil {
+		return nil, err
+	}
+	if len(fileName) == 0 {
+		return nil, fmt.Errorf("could not find docker-compose.yml or docker-compose.yaml in the working directory")
+	}
+	fileNames := []string{fileName}
+	overrideFileName, err := findFirstExistingFile("docker-compose.override.yml", "docker-compose.override.yaml")
+	if err != nil {
+		return nil, err
+	}
+	if len(overrideFileName) > 0 {
+		fileNames = append(fileNames, overrideFileName)
+	}
+	return fileNames, nil
+}
+
+func findFirstExistingFile(fileNames ...string) (string, error) {
+	for _, fileName := range fileNames {
+		_, err := os.Stat(fileName)
+		if err == nil {
+			return fileName, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// loadFile reads and interpolates a single docker compose file, returning its generic structure and version.
+func loadFile(fileName string, valueGetter ValueGetter) (genericMap, *version.Version, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var dataMap genericMap
+	err = yaml.Unmarshal(data, &dataMap)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var ver *version.Version
+	verRaw, hasVer := dataMap["version"]
+	if !hasVer {
+		// Like docker-compose, files without a version use the latest version of the file format.
+		ver = v3_9
+	} else if verStr, ok := verRaw.(string); ok {
+		ver, err = version.NewVersion(verStr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("file %#v has an invalid version: %#v", fileName, verStr)
+		}
+	} else {
+		return nil, nil, fmt.Errorf("file %#v has a version that is not a string", fileName)
+	}
+
+	// Substitute variables with environment variables.
+	err = InterpolateConfig(fileName, dataMap, valueGetter, ver)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Relative paths are relative to the directory of the file they appear in, but this information is lost once files are merged.
+	err = resolveRelativePaths(fileName, dataMap)
+	if err != nil {
+		return nil, nil, err
+	}
+	return dataMap, ver, nil
+}
+
+func resolveRelativePaths(fileName string, dataMap genericMap) error {
+	services, err := asMap(dataMap["services"], "services")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(fileName)
+	for _, serviceRaw := range services {
+		service, ok := serviceRaw.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		if buildRaw, ok := service["build"]; ok && buildRaw != nil {
+			build, err := parseBuildMapping(buildRaw, "build")
+			if err != nil {
+				return err
+			}
+			if context, ok := build["context"].(string); ok && !isRemoteBuildContext(context) {
+				build["context"] = resolvePath(dir, context)
+			}
+			service["build"] = build
+		}
+		if volumes, ok := service["volumes"].([]interface{}); ok {
+			err = resolveVolumePaths(dir, volumes)
+			if err != nil {
+				return err
+			}
+		}
+		if envFileRaw, ok := service["env_file"]; ok && envFileRaw != nil {
+			envFiles := toSlice(envFileRaw)
+			resolved := make([]interface{}, len(envFiles))
+			for i, envFileRaw := range envFiles {
+				envFile, ok := envFileRaw.(string)
+				if !ok {
+					return fmt.Errorf("env_file must be a string or a list of strings")
+				}
+				resolved[i] = resolvePath(dir, envFile)
+			}
+			service["env_file"] = resolved
+		}
+	}
+	return nil
+}
+
+func resolvePath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
 // helper for defer in ensureNoDependsOnCycle
 func (service *Service) clearRecStack() {
 	service.recStack = false
@@ -164,16 +391,24 @@ func ensureNoDependsOnCycle(service *Service) error {
 }
 
 // https://github.com/docker/compose/blob/master/compose/config/config_schema_v2.1.json
-func parseCompose2_1(composeYAML *composeFile2_1, dockerComposeFile *CanonicalComposeFile) error {
+func parseCompose2_1(composeYAML *composeFile2_1, dockerComposeFile *CanonicalComposeFile, valueGetter ValueGetter) error {
 	n := len(composeYAML.Services)
 	if n > 0 {
 		dockerComposeFile.Services = make(map[string]*Service, n)
 		for name, serviceYAML := range composeYAML.Services {
-			service, err := parseServiceYAML2_1(&serviceYAML)
+			service, err := parseServiceYAML2_1(&serviceYAML, valueGetter)
 			if err != nil {
 				return err
 			}
 			service.ServiceName = name
+			service.Volumes, err = parseServiceVolumes(serviceYAML.Volumes, dockerComposeFile.Volumes)
+			if err != nil {
+				return fmt.Errorf("service %s %v", name, err)
+			}
+			service.Networks, err = parseServiceNetworks(&serviceYAML.Networks, dockerComposeFile.Networks)
+			if err != nil {
+				return fmt.Errorf("service %s %v", name, err)
+			}
 			dockerComposeFile.Services[name] = service
 			for dependsOnService := range serviceYAML.DependsOn.Values {
 				if _, ok := composeYAML.Services[dependsOnService]; !ok {
@@ -206,8 +441,9 @@ func parseCompose2_1(composeYAML *composeFile2_1, dockerComposeFile *CanonicalCo
 	return nil
 }
 
-func parseServiceYAML2_1(serviceYAML *service2_1) (*Service, error) {
+func parseServiceYAML2_1(serviceYAML *service2_1, valueGetter ValueGetter) (*Service, error) {
 	service := &Service{
+		Command:    serviceYAML.Command.Values,
 		Entrypoint: serviceYAML.Entrypoint.Values,
 		Image:      serviceYAML.Image,
 		WorkingDir: serviceYAML.WorkingDir,
@@ -219,6 +455,12 @@ func parseServiceYAML2_1(serviceYAML *service2_1) (*Service, error) {
 	}
 	service.Ports = ports
 
+	build, err := parseBuild(serviceYAML.Build, valueGetter)
+	if err != nil {
+		return service, err
+	}
+	service.Build = build
+
 	healthcheck, healthcheckDisabled, err := ParseHealthcheck(serviceYAML.Healthcheck)
 	if err != nil {
 		return service, err
@@ -226,7 +468,52 @@ func parseServiceYAML2_1(serviceYAML *service2_1) (*Service, error) {
 	service.Healthcheck = healthcheck
 	service.HealthcheckDisabled = healthcheckDisabled
 
+	service.Probe, err = parseProbe(serviceYAML.Custom.Probe)
+	if err != nil {
+		return service, err
+	}
+	service.Kind, err = parseKind(serviceYAML.Custom.Kind)
+	if err != nil {
+		return service, err
+	}
+
+	service.Restart, service.RestartMaxRetries, err = parseRestart(serviceYAML.Restart.Value, service.Kind)
+	if err != nil {
+		return service, err
+	}
+
+	service.Pod = serviceYAML.Custom.PodConfig
+	service.ReplicasReady = serviceYAML.Custom.ReplicasReady
+
+	service.Replicas, err = parseReplicas(serviceYAML)
+	if err != nil {
+		return service, err
+	}
+
 	service.Environment = make(map[string]string, len(serviceYAML.Environment.Values))
+
+	// Values of environment take precedence over values of env_file, and values of later env files take precedence over earlier ones.
+	// https://docs.docker.com/compose/compose-file/compose-file-v2/#env_file
+	for _, envFile := range serviceYAML.EnvFile.Values {
+		envFileValues, err := parseEnvFile(envFile)
+		if err != nil {
+			return nil, err
+		}
+		for name, valuePtr := range envFileValues {
+			var value string
+			if valuePtr != nil {
+				value = *valuePtr
+			} else {
+				var ok bool
+				value, ok = valueGetter(name)
+				if !ok {
+					continue
+				}
+			}
+			service.Environment[name] = value
+		}
+	}
+
 	for _, pair := range serviceYAML.Environment.Values {
 		var value string
 		if len(pair.Name) == 0 {
@@ -234,20 +521,18 @@ func parseServiceYAML2_1(serviceYAML *service2_1) (*Service, error) {
 		}
 		if pair.Value == nil {
 			var ok bool
-			value, ok = os.LookupEnv(pair.Name)
+			value, ok = valueGetter(pair.Name)
 			if !ok {
 				continue
 			}
-		} else if pair.Value.StringValue != nil {
-			value = *pair.Value.StringValue
-		} else if pair.Value.IntValue != nil {
-			value = strconv.Itoa(*pair.Value.IntValue)
-		} else if pair.Value.FloatValue != nil {
-			value = strconv.FormatFloat(*pair.Value.FloatValue, 'g', -1, 64)
 		} else {
-			// Environment variables with null values in the YAML are ignored.
-			// This was tested with docker-compose.null-env.yml.
-			continue
+			var ok bool
+			value, ok = pair.Value.stringValue()
+			if !ok {
+				// Environment variables with null values in the YAML are ignored.
+				// This was tested with docker-compose.null-env.yml.
+				continue
+			}
 		}
 		service.Environment[pair.Name] = value
 	}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// DNSModeHostAliases resolves the names of docker compose services via the host aliases of pods, which map each name to the cluster
+	// IP of a Kubernetes service.
+	DNSModeHostAliases = "host_aliases"
+	// DNSModeSubdomain resolves the names of docker compose services via cluster DNS, by giving each pod a hostname and a subdomain
+	// that is governed by a headless service per environment.
+	DNSModeSubdomain = "subdomain"
+
+	DefaultClusterDomain = "cluster.local"
+)
+
+// DNSConfig configures how pods resolve the names of docker compose services.
+type DNSConfig struct {
+	ClusterDomain string `mapdecode:"cluster_domain"`
+	Mode          string `mapdecode:"mode"`
+}
+
+func parseDNSConfig(dnsYAML *DNSConfig, services map[string]*Service) (DNSConfig, error) {
+	dns := DNSConfig{
+		ClusterDomain: DefaultClusterDomain,
+		Mode:          DNSModeHostAliases,
+	}
+	if dnsYAML == nil {
+		return dns, nil
+	}
+	if len(dnsYAML.ClusterDomain) > 0 {
+		dns.ClusterDomain = dnsYAML.ClusterDomain
+	}
+	switch dnsYAML.Mode {
+	case "", DNSModeHostAliases:
+	case DNSModeSubdomain:
+		dns.Mode = DNSModeSubdomain
+		// The names of services become the hostnames of pods.
+		for name, service := range services {
+			if errors := validation.IsDNS1123Label(name); len(errors) > 0 {
+				return dns, fmt.Errorf("x-kube-compose.dns.mode is %s, but the docker compose service named %s is not a valid hostname: %s",
+					DNSModeSubdomain, name, errors[0])
+			}
+			// The hostnames of the pods of StatefulSets are the names of the pods.
+			if service.Kind == KindStatefulSet {
+				return dns, fmt.Errorf("x-kube-compose.dns.mode is %s, but the docker compose service named %s has kind %s",
+					DNSModeSubdomain, name, KindStatefulSet)
+			}
+			// A pod has a single hostname in its subdomain, so network aliases cannot be resolved.
+			for networkName, serviceNetwork := range service.Networks {
+				if len(serviceNetwork.Aliases) > 0 {
+					return dns, fmt.Errorf("x-kube-compose.dns.mode is %s, but the docker compose service named %s has aliases on network %s",
+						DNSModeSubdomain, name, networkName)
+				}
+			}
+		}
+	default:
+		return dns, fmt.Errorf("x-kube-compose.dns.mode must be one of %s and %s, but got %#v", DNSModeHostAliases, DNSModeSubdomain, dnsYAML.Mode)
+	}
+	return dns, nil
+}
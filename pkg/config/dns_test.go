@@ -0,0 +1,57 @@
+package config
+
+import "testing"
+
+func TestParseDNSConfigDefault(t *testing.T) {
+	dns, err := parseDNSConfig(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dns.Mode != DNSModeHostAliases || dns.ClusterDomain != DefaultClusterDomain {
+		t.Fatalf("%#v", dns)
+	}
+}
+
+func TestParseDNSConfigSubdomain(t *testing.T) {
+	services := map[string]*Service{
+		"db": {},
+	}
+	dns, err := parseDNSConfig(&DNSConfig{Mode: DNSModeSubdomain, ClusterDomain: "example.org"}, services)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dns.Mode != DNSModeSubdomain || dns.ClusterDomain != "example.org" {
+		t.Fatalf("%#v", dns)
+	}
+}
+
+func TestParseDNSConfigSubdomainInvalidHostname(t *testing.T) {
+	services := map[string]*Service{
+		"db.internal": {},
+	}
+	_, err := parseDNSConfig(&DNSConfig{Mode: DNSModeSubdomain}, services)
+	if err == nil {
+		t.Fail()
+	}
+}
+
+func TestParseDNSConfigInvalidMode(t *testing.T) {
+	_, err := parseDNSConfig(&DNSConfig{Mode: "mdns"}, nil)
+	if err == nil {
+		t.Fail()
+	}
+}
+
+func TestParseDNSConfigSubdomainAliases(t *testing.T) {
+	services := map[string]*Service{
+		"db": {
+			Networks: map[string]*ServiceNetwork{
+				DefaultNetworkName: {Aliases: []string{"db.internal"}},
+			},
+		},
+	}
+	_, err := parseDNSConfig(&DNSConfig{Mode: DNSModeSubdomain}, services)
+	if err == nil {
+		t.Fail()
+	}
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// DefaultEnvFileName is the name of the file in the project directory that is used for variable substitution in docker compose files.
+// https://docs.docker.com/compose/env-file/
+const DefaultEnvFileName = ".env"
+
+// parseEnvFile parses a file with lines of the form NAME=VALUE, like docker-compose's env_file and .env file. Lines starting with a
+// # and empty lines are ignored. A line without an = sign yields a nil value, which means the value should be taken from the
+// environment. Values are not unquoted, which is consistent with docker.
+func parseEnvFile(fileName string) (map[string]*string, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	values, err := readEnvFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing env file %#v: %v", fileName, err)
+	}
+	return values, nil
+}
+
+func readEnvFile(reader io.Reader) (map[string]*string, error) {
+	values := map[string]*string{}
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimLeft(scanner.Text(), " \t")
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		i := strings.IndexRune(line, '=')
+		if i < 0 {
+			values[strings.TrimRight(line, " \t")] = nil
+			continue
+		}
+		if i == 0 {
+			return nil, fmt.Errorf("line %d has an empty variable name", lineNumber)
+		}
+		value := line[i+1:]
+		values[line[:i]] = &value
+	}
+	return values, scanner.Err()
+}
+
+// newValueGetterFromEnvFile creates a ValueGetter that looks up variables in the environment, and falls back to the env file
+// fileName. Variables of the environment take precedence, which is consistent with docker-compose. If fileName does not exist and
+// required is false then the ValueGetter only looks up variables in the environment.
+func newValueGetterFromEnvFile(fileName string, required bool) (ValueGetter, error) {
+	envFileValues, err := parseEnvFile(fileName)
+	if err != nil {
+		if required || !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return func(name string) (string, bool) {
+		if value, found := os.LookupEnv(name); found {
+			return value, true
+		}
+		if value := envFileValues[name]; value != nil {
+			return *value, true
+		}
+		return "", false
+	}, nil
+}
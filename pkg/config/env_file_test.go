@@ -0,0 +1,43 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadEnvFile(t *testing.T) {
+	values, err := readEnvFile(strings.NewReader("# comment\n\nVAR1=val1\n  VAR2=a=b\nVAR3\nVAR4=\"quoted\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 4 {
+		t.Fatal(values)
+	}
+	if *values["VAR1"] != "val1" || *values["VAR2"] != "a=b" || values["VAR3"] != nil || *values["VAR4"] != "\"quoted\"" {
+		t.Fail()
+	}
+}
+
+func TestReadEnvFileEmptyName(t *testing.T) {
+	_, err := readEnvFile(strings.NewReader("=val1\n"))
+	if err == nil {
+		t.Fail()
+	}
+}
+
+func TestNewValueGetterFromEnvFileOptional(t *testing.T) {
+	valueGetter, err := newValueGetterFromEnvFile("does-not-exist.env", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found := valueGetter("KUBE_COMPOSE_DOES_NOT_EXIST"); found {
+		t.Fail()
+	}
+}
+
+func TestNewValueGetterFromEnvFileRequired(t *testing.T) {
+	_, err := newValueGetterFromEnvFile("does-not-exist.env", true)
+	if err == nil {
+		t.Fail()
+	}
+}
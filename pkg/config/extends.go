@@ -0,0 +1,190 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	version "github.com/hashicorp/go-version"
+)
+
+type extendsKey struct {
+	fileName string
+	service  string
+}
+
+// extendsResolver resolves the extends field of services.
+// https://docs.docker.com/compose/extends/#extending-services
+type extendsResolver struct {
+	chain        []extendsKey
+	files        map[string]genericMap
+	recStack     map[extendsKey]bool
+	resolved     map[extendsKey]map[interface{}]interface{}
+	rootFileName string
+	valueGetter  ValueGetter
+}
+
+// resolveExtends replaces every service in the generic structure dataMap by the service obtained by resolving its extends field (if
+// any). fileName is the file that dataMap was loaded from, and relative paths of files referenced by extends are resolved against its
+// directory, and valueGetter is used to interpolate those files. This must be run after interpolation and after merging files, which is
+// consistent with docker-compose.
+func resolveExtends(fileName string, dataMap genericMap, valueGetter ValueGetter) error {
+	absFileName, err := filepath.Abs(fileName)
+	if err != nil {
+		return err
+	}
+	r := &extendsResolver{
+		files: map[string]genericMap{
+			absFileName: dataMap,
+		},
+		recStack:     map[extendsKey]bool{},
+		resolved:     map[extendsKey]map[interface{}]interface{}{},
+		rootFileName: absFileName,
+		valueGetter:  valueGetter,
+	}
+	services, err := asMap(dataMap["services"], "services")
+	if err != nil {
+		return err
+	}
+	for nameRaw := range services {
+		name, ok := nameRaw.(string)
+		if !ok {
+			continue
+		}
+		service, err := r.resolve(extendsKey{
+			fileName: absFileName,
+			service:  name,
+		})
+		if err != nil {
+			return err
+		}
+		services[name] = service
+	}
+	return nil
+}
+
+func (r *extendsResolver) formatKey(key extendsKey) string {
+	if key.fileName == r.rootFileName {
+		return key.service
+	}
+	return fmt.Sprintf("%s (%s)", key.service, key.fileName)
+}
+
+func (r *extendsResolver) formatChain(keys []extendsKey) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = r.formatKey(key)
+	}
+	return strings.Join(parts, " extends ")
+}
+
+func (r *extendsResolver) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%s (while resolving %s)", fmt.Sprintf(format, args...), r.formatChain(r.chain))
+}
+
+func (r *extendsResolver) getServices(fileName string) (map[interface{}]interface{}, error) {
+	dataMap, ok := r.files[fileName]
+	if !ok {
+		var ver *version.Version
+		var err error
+		dataMap, ver, err = loadFile(fileName, r.valueGetter)
+		if err != nil {
+			return nil, r.errorf("could not load file %#v: %v", fileName, err)
+		}
+		err = validateVersion(ver, dataMap)
+		if err != nil {
+			return nil, r.errorf("invalid file %#v: %v", fileName, err)
+		}
+		r.files[fileName] = dataMap
+	}
+	return asMap(dataMap["services"], fmt.Sprintf("services of file %#v", fileName))
+}
+
+// resolve uses the same cycle detection algorithm as ensureNoDependsOnCycle.
+func (r *extendsResolver) resolve(key extendsKey) (map[interface{}]interface{}, error) {
+	if service, ok := r.resolved[key]; ok {
+		return service, nil
+	}
+	if r.recStack[key] {
+		return nil, fmt.Errorf("circular reference in extends: %s", r.formatChain(append(r.chain, key)))
+	}
+	r.recStack[key] = true
+	r.chain = append(r.chain, key)
+	defer func() {
+		delete(r.recStack, key)
+		r.chain = r.chain[:len(r.chain)-1]
+	}()
+
+	services, err := r.getServices(key.fileName)
+	if err != nil {
+		return nil, err
+	}
+	serviceRaw, ok := services[key.service]
+	if !ok {
+		return nil, r.errorf("cannot extend service %#v: service does not exist in file %#v", key.service, key.fileName)
+	}
+	service, err := asMap(serviceRaw, fmt.Sprintf("service %s", key.service))
+	if err != nil {
+		return nil, r.errorf("%v", err)
+	}
+	extendsRaw, hasExtends := service["extends"]
+	if !hasExtends {
+		r.resolved[key] = service
+		return service, nil
+	}
+	baseKey, err := r.parseExtends(extendsRaw, key.fileName)
+	if err != nil {
+		return nil, err
+	}
+	base, err := r.resolve(baseKey)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range []string{"depends_on", "links", "volumes_from"} {
+		if _, ok := base[field]; ok {
+			return nil, r.errorf("cannot extend service %#v: services with %#v cannot be extended", baseKey.service, field)
+		}
+	}
+	override := make(map[interface{}]interface{}, len(service))
+	for field, value := range service {
+		if field != "extends" {
+			override[field] = value
+		}
+	}
+	merged, err := mergeServices(base, override)
+	if err != nil {
+		return nil, r.errorf("%v", err)
+	}
+	r.resolved[key] = merged
+	return merged, nil
+}
+
+func (r *extendsResolver) parseExtends(extendsRaw interface{}, fileName string) (extendsKey, error) {
+	key := extendsKey{
+		fileName: fileName,
+	}
+	if service, ok := extendsRaw.(string); ok {
+		key.service = service
+		return key, nil
+	}
+	extends, err := asMap(extendsRaw, "extends")
+	if err != nil {
+		return key, r.errorf("%v", err)
+	}
+	service, ok := extends["service"].(string)
+	if !ok || len(service) == 0 {
+		return key, r.errorf("field \"service\" of extends must be a non-empty string")
+	}
+	key.service = service
+	if fileRaw, ok := extends["file"]; ok {
+		file, ok := fileRaw.(string)
+		if !ok || len(file) == 0 {
+			return key, r.errorf("field \"file\" of extends must be a non-empty string")
+		}
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(fileName), file)
+		}
+		key.fileName = filepath.Clean(file)
+	}
+	return key, nil
+}
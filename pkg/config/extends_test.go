@@ -0,0 +1,123 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestResolveExtendsSameFile(t *testing.T) {
+	dataMap := genericMap{
+		"services": map[interface{}]interface{}{
+			"generic-service": map[interface{}]interface{}{
+				"image": "ubuntu:latest",
+				"environment": map[interface{}]interface{}{
+					"ENVVAR_EXTENDS": "test",
+				},
+			},
+			"permission-service": map[interface{}]interface{}{
+				"extends": map[interface{}]interface{}{
+					"service": "generic-service",
+				},
+				"environment": map[interface{}]interface{}{
+					"ENVVAR_STR": "str",
+				},
+			},
+		},
+	}
+	err := resolveExtends("docker-compose.yml", dataMap, mapValueGetter(map[string]string{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := dataMap["services"].(map[interface{}]interface{})["permission-service"].(map[interface{}]interface{})
+	if _, ok := service["extends"]; ok {
+		t.Fatal(service)
+	}
+	if service["image"] != "ubuntu:latest" {
+		t.Fatal(service)
+	}
+	environment := service["environment"].(map[interface{}]interface{})
+	if environment["ENVVAR_EXTENDS"] != "test" || environment["ENVVAR_STR"] != "str" {
+		t.Fatal(environment)
+	}
+}
+
+func TestResolveExtendsEnvironmentPassThrough(t *testing.T) {
+	dataMap := genericMap{
+		"services": map[interface{}]interface{}{
+			"generic-service": map[interface{}]interface{}{
+				"image": "ubuntu:latest",
+				"environment": []interface{}{
+					"HOST_VAR",
+				},
+			},
+			"permission-service": map[interface{}]interface{}{
+				"extends": "generic-service",
+				"environment": []interface{}{
+					"ENVVAR_STR=str",
+				},
+			},
+		},
+	}
+	err := resolveExtends("docker-compose.yml", dataMap, mapValueGetter(map[string]string{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := dataMap["services"].(map[interface{}]interface{})["permission-service"].(map[interface{}]interface{})
+	environment := service["environment"].([]interface{})
+	if len(environment) != 2 || environment[0] != "ENVVAR_STR=str" || environment[1] != "HOST_VAR" {
+		t.Fatal(environment)
+	}
+}
+
+func TestResolveExtendsCycle(t *testing.T) {
+	dataMap := genericMap{
+		"services": map[interface{}]interface{}{
+			"a": map[interface{}]interface{}{
+				"extends": "b",
+			},
+			"b": map[interface{}]interface{}{
+				"extends": "c",
+			},
+			"c": map[interface{}]interface{}{
+				"extends": "a",
+			},
+		},
+	}
+	err := resolveExtends("docker-compose.yml", dataMap, mapValueGetter(map[string]string{}))
+	if err == nil {
+		t.Fail()
+	}
+}
+
+func TestResolveExtendsNonExistingService(t *testing.T) {
+	dataMap := genericMap{
+		"services": map[interface{}]interface{}{
+			"a": map[interface{}]interface{}{
+				"extends": "b",
+			},
+		},
+	}
+	err := resolveExtends("docker-compose.yml", dataMap, mapValueGetter(map[string]string{}))
+	if err == nil {
+		t.Fail()
+	}
+}
+
+func TestResolveExtendsDependsOnNotAllowed(t *testing.T) {
+	dataMap := genericMap{
+		"services": map[interface{}]interface{}{
+			"a": map[interface{}]interface{}{
+				"extends": "b",
+			},
+			"b": map[interface{}]interface{}{
+				"depends_on": []interface{}{
+					"c",
+				},
+			},
+			"c": map[interface{}]interface{}{},
+		},
+	}
+	err := resolveExtends("docker-compose.yml", dataMap, mapValueGetter(map[string]string{}))
+	if err == nil {
+		t.Fail()
+	}
+}
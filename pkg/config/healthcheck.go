@@ -14,6 +14,15 @@ const (
 	HealthcheckDefaultRetries  = 3
 )
 
+// ProbesConfig configures which Kubernetes probes are derived from the docker healthcheck of a service. Readiness probes are always
+// derived, because they implement depends_on condition service_healthy.
+type ProbesConfig struct {
+	Liveness bool `mapdecode:"liveness"`
+	Startup  bool `mapdecode:"startup"`
+	// Translate enables the translation of common healthchecks into HTTP GET and TCP socket probes, see TranslateHealthcheck.
+	Translate bool `mapdecode:"translate"`
+}
+
 type Healthcheck struct {
 	Interval    time.Duration
 	IsShell     bool
@@ -54,9 +63,6 @@ func ParseHealthcheck(healthcheckYAML *ServiceHealthcheck) (*Healthcheck, bool,
 	}
 	healthcheck.Test = test[1:]
 
-	// We do not set StartPeriod because it is unsupported in docker-compose 2.1 and
-	// should therefore  be treated as 0.
-
 	// time.ParseDuration supports a superset of duration compared to docker-compose:
 	// https://golang.org/pkg/time/#Duration
 	// https://docs.docker.com/compose/compose-file/compose-file-v2/#specifying-durations
@@ -86,6 +92,18 @@ func ParseHealthcheck(healthcheckYAML *ServiceHealthcheck) (*Healthcheck, bool,
 		healthcheck.Timeout = HealthcheckDefaultTimeout
 	}
 
+	// StartPeriod is only supported by docker compose file format 2.3+ and 3.4+ (see validateVersion), and defaults to 0.
+	if healthcheckYAML.StartPeriod != nil {
+		startPeriod, err := time.ParseDuration(*healthcheckYAML.StartPeriod)
+		if err != nil {
+			return nil, false, err
+		}
+		if startPeriod < 0 {
+			return nil, false, fmt.Errorf("field \"start_period\" of Healthcheck must not be negative")
+		}
+		healthcheck.StartPeriod = startPeriod
+	}
+
 	if healthcheckYAML.Retries != nil {
 		healthcheck.Retries = *healthcheckYAML.Retries
 	} else {
@@ -94,3 +112,10 @@ func ParseHealthcheck(healthcheckYAML *ServiceHealthcheck) (*Healthcheck, bool,
 
 	return healthcheck, false, nil
 }
+
+func parseProbesConfig(probesYAML *ProbesConfig) (ProbesConfig, error) {
+	if probesYAML == nil {
+		return ProbesConfig{}, nil
+	}
+	return *probesYAML, nil
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseHealthcheckStartPeriod(t *testing.T) {
+	startPeriod := "1m30s"
+	healthcheck, disabled, err := ParseHealthcheck(&ServiceHealthcheck{
+		StartPeriod: &startPeriod,
+		Test: HealthcheckTest{
+			Values: []string{HealthcheckCommandShell, "true"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if disabled || healthcheck.StartPeriod != 90*time.Second {
+		t.Fatalf("%#v", healthcheck)
+	}
+}
+
+func TestParseHealthcheckNegativeStartPeriod(t *testing.T) {
+	startPeriod := "-1s"
+	_, _, err := ParseHealthcheck(&ServiceHealthcheck{
+		StartPeriod: &startPeriod,
+		Test: HealthcheckTest{
+			Values: []string{HealthcheckCommandShell, "true"},
+		},
+	})
+	if err == nil {
+		t.Fail()
+	}
+}
+
+func TestParseProbesConfig(t *testing.T) {
+	probes, err := parseProbesConfig(&ProbesConfig{Liveness: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !probes.Liveness {
+		t.Fatalf("%#v", probes)
+	}
+}
+
+func TestParseProbesConfigStartup(t *testing.T) {
+	probes, err := parseProbesConfig(&ProbesConfig{Startup: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !probes.Startup {
+		t.Fatalf("%#v", probes)
+	}
+}
@@ -67,7 +67,7 @@ func (c *configInterpolator) run() error {
 
 func (c *configInterpolator) interpolateSectionByName(name string) {
 	if sectionRaw, ok := c.config[name]; ok {
-		if section, ok := sectionRaw.(genericMap); ok {
+		if section, ok := toGenericMap(sectionRaw); ok {
 			c.interpolateSection(section, (path{}).appendStr(name))
 		}
 	}
@@ -205,6 +205,17 @@ func Interpolate(str string, valueGetter ValueGetter, version bool) (string, err
 	return sb.String(), nil
 }
 
+// toGenericMap is needed because yaml.v2 decodes nested mappings as map[interface{}]interface{} rather than genericMap.
+func toGenericMap(obj interface{}) (genericMap, bool) {
+	switch m := obj.(type) {
+	case genericMap:
+		return m, true
+	case map[interface{}]interface{}:
+		return genericMap(m), true
+	}
+	return nil, false
+}
+
 // IsASCIILetter returns true if and only if b is the ASCII code for a letter.
 func IsASCIILetter(b byte) bool {
 	return (byte('a') <= b && b <= byte('z')) || (byte('A') <= b && b <= byte('Z'))
@@ -223,7 +234,7 @@ func (c *configInterpolator) interpolateRecursive(obj interface{}, p path) inter
 		}
 		return str2
 	}
-	if m, ok := obj.(genericMap); ok {
+	if m, ok := toGenericMap(obj); ok {
 		for keyRaw, val := range m {
 			if key, ok := keyRaw.(string); ok {
 				childPath := p.appendStr(key)
@@ -231,7 +242,7 @@ func (c *configInterpolator) interpolateRecursive(obj interface{}, p path) inter
 				childPath.pop()
 			}
 		}
-		return m
+		return obj
 	}
 	if obj != nil && reflect.TypeOf(obj).Kind() == reflect.Slice {
 		slicev := reflect.ValueOf(obj)
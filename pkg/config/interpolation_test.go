@@ -161,3 +161,24 @@ func TestInterpolateRecursiveSlice(t *testing.T) {
 	}
 	t.Fail()
 }
+
+func TestInterpolateConfigNestedMap(t *testing.T) {
+	m := map[string]string{
+		"TAG": "10",
+	}
+	config := genericMap{
+		"services": map[interface{}]interface{}{
+			"db": map[interface{}]interface{}{
+				"image": "postgres:$TAG",
+			},
+		},
+	}
+	err := InterpolateConfig("docker-compose.yml", config, mapValueGetter(m), v2_1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := config["services"].(map[interface{}]interface{})["db"].(map[interface{}]interface{})
+	if db["image"] != "postgres:10" {
+		t.Fatal(db["image"])
+	}
+}
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"fmt"
+)
+
+// Values of x-kube-compose.kind, the kind of Kubernetes workload that runs the pods of a service.
+const (
+	KindDeployment  = "deployment"
+	KindJob         = "job"
+	KindPod         = "pod"
+	KindStatefulSet = "statefulset"
+)
+
+// parseKind validates the kind of a service, which defaults to bare pods.
+func parseKind(kind string) (string, error) {
+	switch kind {
+	case "":
+		return KindPod, nil
+	case KindDeployment, KindJob, KindPod, KindStatefulSet:
+		return kind, nil
+	}
+	return "", fmt.Errorf("x-kube-compose.kind must be one of %s, %s, %s and %s, but got %#v", KindPod, KindJob, KindDeployment,
+		KindStatefulSet, kind)
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestParseKindDefault(t *testing.T) {
+	kind, err := parseKind("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kind != KindPod {
+		t.Fatal(kind)
+	}
+}
+
+func TestParseKindValid(t *testing.T) {
+	for _, value := range []string{KindDeployment, KindJob, KindPod, KindStatefulSet} {
+		kind, err := parseKind(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kind != value {
+			t.Fatal(kind)
+		}
+	}
+}
+
+func TestParseKindInvalid(t *testing.T) {
+	_, err := parseKind("daemonset")
+	if err == nil {
+		t.Fail()
+	}
+}
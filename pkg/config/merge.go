@@ -0,0 +1,345 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// passThrough is the value of an item without a separator in the list syntax of environment (e.g. "- FOO"), whose value is taken from
+// the host environment. Such items cannot be represented by nil, because null values of the mapping syntax are ignored.
+type passThrough struct{}
+
+// mergeComposeFiles merges the generic structure of override into base, implementing the semantics of docker-compose's -f flag when
+// specified multiple times. Both arguments must be already interpolated. base is modified and returned.
+// https://docs.docker.com/compose/extends/#adding-and-overriding-configuration
+func mergeComposeFiles(base, override genericMap) (genericMap, error) {
+	for keyRaw, overrideValue := range override {
+		key, ok := keyRaw.(string)
+		if !ok {
+			continue
+		}
+		switch key {
+		case "services":
+			baseServices, err := asMap(base[key], key)
+			if err != nil {
+				return nil, err
+			}
+			overrideServices, err := asMap(overrideValue, key)
+			if err != nil {
+				return nil, err
+			}
+			if baseServices == nil {
+				baseServices = map[interface{}]interface{}{}
+			}
+			for nameRaw, overrideServiceRaw := range overrideServices {
+				overrideService, err := asMap(overrideServiceRaw, fmt.Sprintf("service %v", nameRaw))
+				if err != nil {
+					return nil, err
+				}
+				baseService, err := asMap(baseServices[nameRaw], fmt.Sprintf("service %v", nameRaw))
+				if err != nil {
+					return nil, err
+				}
+				if baseService == nil {
+					baseServices[nameRaw] = overrideService
+					continue
+				}
+				mergedService, err := mergeServices(baseService, overrideService)
+				if err != nil {
+					return nil, fmt.Errorf("error while merging service %v: %v", nameRaw, err)
+				}
+				baseServices[nameRaw] = mergedService
+			}
+			base[key] = baseServices
+		case "volumes", "networks", "secrets", "configs", "x-kube-compose":
+			merged, err := mergeShallow(base[key], overrideValue, key)
+			if err != nil {
+				return nil, err
+			}
+			base[key] = merged
+		default:
+			base[key] = overrideValue
+		}
+	}
+	return base, nil
+}
+
+// mergeServices merges the generic structure of the service override into base, following docker-compose's merge_service_dicts.
+// https://github.com/docker/compose/blob/master/compose/config/config.py
+func mergeServices(base, override map[interface{}]interface{}) (map[interface{}]interface{}, error) {
+	merged := make(map[interface{}]interface{}, len(base)+len(override))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for keyRaw, overrideValue := range override {
+		key, ok := keyRaw.(string)
+		if !ok {
+			continue
+		}
+		baseValue, hasBaseValue := base[key]
+		if !hasBaseValue || baseValue == nil || overrideValue == nil {
+			merged[key] = overrideValue
+			continue
+		}
+		var err error
+		switch key {
+		case "environment", "labels", "extra_hosts":
+			merged[key], err = mergeKeyValueMappings(baseValue, overrideValue, key)
+		case "depends_on":
+			merged[key], err = mergeMappings(baseValue, overrideValue, key, parseDependsOnMapping)
+		case "build":
+			merged[key], err = mergeMappings(baseValue, overrideValue, key, parseBuildMapping)
+		case "networks", "x-kube-compose":
+			merged[key], err = mergeMappings(baseValue, overrideValue, key, parseNetworksMapping)
+		case "healthcheck":
+			merged[key], err = mergeHealthchecks(baseValue, overrideValue)
+		case "volumes", "devices":
+			merged[key], err = mergePathMappings(baseValue, overrideValue, key)
+		case "ports", "expose", "cap_add", "cap_drop", "external_links", "volumes_from":
+			merged[key] = mergeUniqueItems(asSlice(baseValue), asSlice(overrideValue))
+		case "dns", "dns_search", "env_file", "tmpfs":
+			merged[key] = mergeUniqueItems(toSlice(baseValue), toSlice(overrideValue))
+		default:
+			merged[key] = overrideValue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return merged, nil
+}
+
+func asMap(value interface{}, what string) (map[interface{}]interface{}, error) {
+	switch m := value.(type) {
+	case nil:
+		return nil, nil
+	case genericMap:
+		return m, nil
+	case map[interface{}]interface{}:
+		return m, nil
+	}
+	return nil, fmt.Errorf("%s must be a mapping", what)
+}
+
+func asSlice(value interface{}) []interface{} {
+	if slice, ok := value.([]interface{}); ok {
+		return slice
+	}
+	return nil
+}
+
+// toSlice converts a field that can be either a string or a list of strings to a list.
+func toSlice(value interface{}) []interface{} {
+	if slice, ok := value.([]interface{}); ok {
+		return slice
+	}
+	return []interface{}{value}
+}
+
+func mergeShallow(base, override interface{}, what string) (map[interface{}]interface{}, error) {
+	baseMap, err := asMap(base, what)
+	if err != nil {
+		return nil, err
+	}
+	overrideMap, err := asMap(override, what)
+	if err != nil {
+		return nil, err
+	}
+	merged := make(map[interface{}]interface{}, len(baseMap)+len(overrideMap))
+	for key, value := range baseMap {
+		merged[key] = value
+	}
+	for key, value := range overrideMap {
+		merged[key] = value
+	}
+	return merged, nil
+}
+
+func mergeMappings(base, override interface{}, what string, parse func(interface{}, string) (map[interface{}]interface{}, error)) (interface{}, error) {
+	baseMap, err := parse(base, what)
+	if err != nil {
+		return nil, err
+	}
+	overrideMap, err := parse(override, what)
+	if err != nil {
+		return nil, err
+	}
+	return mergeShallow(baseMap, overrideMap, what)
+}
+
+// mergeKeyValueMappings merges environment, labels or extra_hosts. If an item without a separator remains after merging, the result is
+// re-emitted in the list syntax so that the item keeps its meaning.
+func mergeKeyValueMappings(base, override interface{}, what string) (interface{}, error) {
+	mergedRaw, err := mergeMappings(base, override, what, parseKeyValueMapping)
+	if err != nil {
+		return nil, err
+	}
+	merged := mergedRaw.(map[interface{}]interface{})
+	hasPassThrough := false
+	for _, value := range merged {
+		if _, ok := value.(passThrough); ok {
+			hasPassThrough = true
+			break
+		}
+	}
+	if !hasPassThrough {
+		return merged, nil
+	}
+	separator := keyValueSeparator(what)
+	slice := make([]interface{}, 0, len(merged))
+	for key, value := range merged {
+		switch value.(type) {
+		case passThrough:
+			slice = append(slice, fmt.Sprint(key))
+		case nil:
+			// Null values of the mapping syntax are ignored, so they need not be represented in the list syntax.
+		default:
+			slice = append(slice, fmt.Sprintf("%v%s%v", key, separator, value))
+		}
+	}
+	sort.Slice(slice, func(i, j int) bool {
+		return slice[i].(string) < slice[j].(string)
+	})
+	return slice, nil
+}
+
+func keyValueSeparator(what string) string {
+	if what == "extra_hosts" {
+		return ":"
+	}
+	return "="
+}
+
+// parseKeyValueMapping parses a mapping or a list of "key=value" or "key" strings (used for environment, labels and extra_hosts).
+func parseKeyValueMapping(value interface{}, what string) (map[interface{}]interface{}, error) {
+	slice, ok := value.([]interface{})
+	if !ok {
+		return asMap(value, what)
+	}
+	separator := keyValueSeparator(what)
+	m := make(map[interface{}]interface{}, len(slice))
+	for _, itemRaw := range slice {
+		item, ok := itemRaw.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a mapping or a list of strings", what)
+		}
+		i := strings.Index(item, separator)
+		if i < 0 {
+			m[item] = passThrough{}
+		} else {
+			m[item[:i]] = item[i+1:]
+		}
+	}
+	return m, nil
+}
+
+// parseDependsOnMapping parses depends_on, normalizing the list syntax to the mapping syntax.
+func parseDependsOnMapping(value interface{}, what string) (map[interface{}]interface{}, error) {
+	slice, ok := value.([]interface{})
+	if !ok {
+		return asMap(value, what)
+	}
+	m := make(map[interface{}]interface{}, len(slice))
+	for _, service := range slice {
+		m[service] = map[interface{}]interface{}{
+			"condition": "service_started",
+		}
+	}
+	return m, nil
+}
+
+// parseBuildMapping parses build, normalizing the string syntax (the context) to the mapping syntax.
+func parseBuildMapping(value interface{}, what string) (map[interface{}]interface{}, error) {
+	if str, ok := value.(string); ok {
+		return map[interface{}]interface{}{
+			"context": str,
+		}, nil
+	}
+	return asMap(value, what)
+}
+
+// parseNetworksMapping parses networks, normalizing the list syntax to the mapping syntax.
+func parseNetworksMapping(value interface{}, what string) (map[interface{}]interface{}, error) {
+	slice, ok := value.([]interface{})
+	if !ok {
+		return asMap(value, what)
+	}
+	m := make(map[interface{}]interface{}, len(slice))
+	for _, network := range slice {
+		m[network] = nil
+	}
+	return m, nil
+}
+
+func mergeHealthchecks(base, override interface{}) (interface{}, error) {
+	overrideMap, err := asMap(override, "healthcheck")
+	if err != nil {
+		return nil, err
+	}
+	if disable, ok := overrideMap["disable"].(bool); ok && disable {
+		return overrideMap, nil
+	}
+	baseMap, err := asMap(base, "healthcheck")
+	if err != nil {
+		return nil, err
+	}
+	if disable, ok := baseMap["disable"].(bool); ok && disable {
+		return overrideMap, nil
+	}
+	return mergeShallow(baseMap, overrideMap, "healthcheck")
+}
+
+// mergePathMappings merges volumes (and devices) keyed by their container path, as docker-compose does.
+func mergePathMappings(base, override interface{}, what string) (interface{}, error) {
+	baseSlice := asSlice(base)
+	overrideSlice := asSlice(override)
+	merged := make([]interface{}, 0, len(baseSlice)+len(overrideSlice))
+	indexFromPath := map[string]int{}
+	for _, slice := range [][]interface{}{baseSlice, overrideSlice} {
+		for _, item := range slice {
+			path, err := getContainerPath(item, what)
+			if err != nil {
+				return nil, err
+			}
+			if i, ok := indexFromPath[path]; ok {
+				merged[i] = item
+				continue
+			}
+			indexFromPath[path] = len(merged)
+			merged = append(merged, item)
+		}
+	}
+	return merged, nil
+}
+
+func getContainerPath(item interface{}, what string) (string, error) {
+	switch v := item.(type) {
+	case string:
+		parts := strings.Split(v, ":")
+		if len(parts) >= 2 {
+			return parts[1], nil
+		}
+		return parts[0], nil
+	case map[interface{}]interface{}:
+		if target, ok := v["target"].(string); ok {
+			return target, nil
+		}
+	}
+	return "", fmt.Errorf("%s contains an item with an unsupported format: %v", what, item)
+}
+
+func mergeUniqueItems(base, override []interface{}) []interface{} {
+	merged := make([]interface{}, 0, len(base)+len(override))
+	seen := map[string]bool{}
+	for _, slice := range [][]interface{}{base, override} {
+		for _, item := range slice {
+			key := fmt.Sprintf("%#v", item)
+			if !seen[key] {
+				seen[key] = true
+				merged = append(merged, item)
+			}
+		}
+	}
+	return merged
+}
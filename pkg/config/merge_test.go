@@ -0,0 +1,188 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeServicesScalarOverride(t *testing.T) {
+	base := map[interface{}]interface{}{
+		"image":       "ubuntu:16.04",
+		"working_dir": "/app",
+	}
+	override := map[interface{}]interface{}{
+		"image": "ubuntu:18.04",
+	}
+	merged, err := mergeServices(base, override)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged["image"] != "ubuntu:18.04" || merged["working_dir"] != "/app" {
+		t.Fatal(merged)
+	}
+}
+
+func TestMergeServicesEnvironment(t *testing.T) {
+	base := map[interface{}]interface{}{
+		"environment": []interface{}{
+			"VAR1=a",
+			"VAR2=b",
+		},
+	}
+	override := map[interface{}]interface{}{
+		"environment": map[interface{}]interface{}{
+			"VAR2": "c",
+			"VAR3": nil,
+		},
+	}
+	merged, err := mergeServices(base, override)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[interface{}]interface{}{
+		"VAR1": "a",
+		"VAR2": "c",
+		"VAR3": nil,
+	}
+	if !reflect.DeepEqual(merged["environment"], expected) {
+		t.Fatal(merged["environment"])
+	}
+}
+
+func TestMergeServicesEnvironmentPassThrough(t *testing.T) {
+	base := map[interface{}]interface{}{
+		"environment": []interface{}{
+			"VAR1",
+			"VAR2=b",
+			"VAR3",
+		},
+	}
+	override := map[interface{}]interface{}{
+		"environment": map[interface{}]interface{}{
+			"VAR2": "c",
+			"VAR3": "d",
+			"VAR4": nil,
+		},
+	}
+	merged, err := mergeServices(base, override)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []interface{}{
+		"VAR1",
+		"VAR2=c",
+		"VAR3=d",
+	}
+	if !reflect.DeepEqual(merged["environment"], expected) {
+		t.Fatal(merged["environment"])
+	}
+}
+
+func TestMergeServicesPortsUnique(t *testing.T) {
+	base := map[interface{}]interface{}{
+		"ports": []interface{}{
+			"8080",
+			8081,
+		},
+	}
+	override := map[interface{}]interface{}{
+		"ports": []interface{}{
+			8081,
+			"9090:9090",
+		},
+	}
+	merged, err := mergeServices(base, override)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []interface{}{
+		"8080",
+		8081,
+		"9090:9090",
+	}
+	if !reflect.DeepEqual(merged["ports"], expected) {
+		t.Fatal(merged["ports"])
+	}
+}
+
+func TestMergeServicesDependsOnUnion(t *testing.T) {
+	base := map[interface{}]interface{}{
+		"depends_on": []interface{}{
+			"db",
+		},
+	}
+	override := map[interface{}]interface{}{
+		"depends_on": map[interface{}]interface{}{
+			"cache": map[interface{}]interface{}{
+				"condition": "service_healthy",
+			},
+		},
+	}
+	merged, err := mergeServices(base, override)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[interface{}]interface{}{
+		"db": map[interface{}]interface{}{
+			"condition": "service_started",
+		},
+		"cache": map[interface{}]interface{}{
+			"condition": "service_healthy",
+		},
+	}
+	if !reflect.DeepEqual(merged["depends_on"], expected) {
+		t.Fatal(merged["depends_on"])
+	}
+}
+
+func TestMergeServicesVolumesByContainerPath(t *testing.T) {
+	base := map[interface{}]interface{}{
+		"volumes": []interface{}{
+			"./a:/data",
+			"./b:/config:ro",
+		},
+	}
+	override := map[interface{}]interface{}{
+		"volumes": []interface{}{
+			"./c:/data",
+		},
+	}
+	merged, err := mergeServices(base, override)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []interface{}{
+		"./c:/data",
+		"./b:/config:ro",
+	}
+	if !reflect.DeepEqual(merged["volumes"], expected) {
+		t.Fatal(merged["volumes"])
+	}
+}
+
+func TestMergeComposeFilesAddsServices(t *testing.T) {
+	base := genericMap{
+		"version": "2.1",
+		"services": map[interface{}]interface{}{
+			"a": map[interface{}]interface{}{
+				"image": "a",
+			},
+		},
+	}
+	override := genericMap{
+		"version": "2.1",
+		"services": map[interface{}]interface{}{
+			"b": map[interface{}]interface{}{
+				"image": "b",
+			},
+		},
+	}
+	merged, err := mergeComposeFiles(base, override)
+	if err != nil {
+		t.Fatal(err)
+	}
+	services := merged["services"].(map[interface{}]interface{})
+	if len(services) != 2 {
+		t.Fatal(services)
+	}
+}
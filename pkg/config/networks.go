@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/uber-go/mapdecode"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// DefaultNetworkName is the name of the network of services that do not specify networks.
+const DefaultNetworkName = "default"
+
+// Network is a network declared in the top-level networks section of a docker compose file (or the implicit default network).
+type Network struct {
+	Name string
+}
+
+// ServiceNetwork is the configuration of a service on one of its networks.
+type ServiceNetwork struct {
+	Aliases []string
+}
+
+// networkYAML is a value of the top-level networks section.
+// https://docs.docker.com/compose/compose-file/compose-file-v2/#network-configuration-reference
+type networkYAML struct {
+	External interface{} `mapdecode:"external"`
+}
+
+type serviceNetworkYAML struct {
+	Aliases []string `mapdecode:"aliases"`
+}
+
+// serviceNetworksYAML is the networks field of a service, in the list syntax or the mapping syntax.
+type serviceNetworksYAML struct {
+	Values map[string]*serviceNetworkYAML
+}
+
+func (n *serviceNetworksYAML) Decode(into mapdecode.Into) error {
+	var names []string
+	err := into(&names)
+	if err == nil {
+		n.Values = make(map[string]*serviceNetworkYAML, len(names))
+		for _, name := range names {
+			n.Values[name] = nil
+		}
+		return nil
+	}
+	return into(&n.Values)
+}
+
+func parseNetworks(networksYAML map[string]*networkYAML) (map[string]*Network, error) {
+	networks := make(map[string]*Network, len(networksYAML)+1)
+	for name, networkYAML := range networksYAML {
+		if networkYAML != nil && networkYAML.External != nil && networkYAML.External != false {
+			return nil, fmt.Errorf("network %s is external, but external networks are not supported", name)
+		}
+		networks[name] = &Network{
+			Name: name,
+		}
+	}
+	if _, ok := networks[DefaultNetworkName]; !ok {
+		networks[DefaultNetworkName] = &Network{
+			Name: DefaultNetworkName,
+		}
+	}
+	return networks, nil
+}
+
+func parseServiceNetworks(serviceNetworksYAML *serviceNetworksYAML, networks map[string]*Network) (map[string]*ServiceNetwork, error) {
+	if serviceNetworksYAML == nil || len(serviceNetworksYAML.Values) == 0 {
+		return map[string]*ServiceNetwork{
+			DefaultNetworkName: {},
+		}, nil
+	}
+	serviceNetworks := make(map[string]*ServiceNetwork, len(serviceNetworksYAML.Values))
+	for name, serviceNetworkYAML := range serviceNetworksYAML.Values {
+		if _, ok := networks[name]; !ok {
+			return nil, fmt.Errorf("refers to a non-existing network: %s", name)
+		}
+		serviceNetwork := &ServiceNetwork{}
+		if serviceNetworkYAML != nil {
+			for _, alias := range serviceNetworkYAML.Aliases {
+				// Aliases become hostnames of host aliases.
+				if errors := validation.IsDNS1123Subdomain(alias); len(errors) > 0 {
+					return nil, fmt.Errorf("has an invalid alias %s on network %s: %s", alias, name, errors[0])
+				}
+			}
+			serviceNetwork.Aliases = serviceNetworkYAML.Aliases
+		}
+		serviceNetworks[name] = serviceNetwork
+	}
+	return serviceNetworks, nil
+}
@@ -0,0 +1,76 @@
+package config
+
+import "testing"
+
+func TestParseNetworksDefault(t *testing.T) {
+	networks, err := parseNetworks(map[string]*networkYAML{
+		"backend": nil,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(networks) != 2 || networks[DefaultNetworkName] == nil || networks["backend"] == nil {
+		t.Fatalf("%#v", networks)
+	}
+}
+
+func TestParseNetworksExternal(t *testing.T) {
+	_, err := parseNetworks(map[string]*networkYAML{
+		"backend": {External: true},
+	})
+	if err == nil {
+		t.Fail()
+	}
+}
+
+func TestParseServiceNetworksDefault(t *testing.T) {
+	serviceNetworks, err := parseServiceNetworks(&serviceNetworksYAML{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(serviceNetworks) != 1 || serviceNetworks[DefaultNetworkName] == nil {
+		t.Fatalf("%#v", serviceNetworks)
+	}
+}
+
+func TestParseServiceNetworksAliases(t *testing.T) {
+	networks := map[string]*Network{
+		"backend": {Name: "backend"},
+	}
+	serviceNetworks, err := parseServiceNetworks(&serviceNetworksYAML{
+		Values: map[string]*serviceNetworkYAML{
+			"backend": {Aliases: []string{"db.internal"}},
+		},
+	}, networks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(serviceNetworks) != 1 || len(serviceNetworks["backend"].Aliases) != 1 || serviceNetworks["backend"].Aliases[0] != "db.internal" {
+		t.Fatalf("%#v", serviceNetworks)
+	}
+}
+
+func TestParseServiceNetworksNonExisting(t *testing.T) {
+	_, err := parseServiceNetworks(&serviceNetworksYAML{
+		Values: map[string]*serviceNetworkYAML{
+			"backend": nil,
+		},
+	}, map[string]*Network{})
+	if err == nil {
+		t.Fail()
+	}
+}
+
+func TestParseServiceNetworksInvalidAlias(t *testing.T) {
+	networks := map[string]*Network{
+		"backend": {Name: "backend"},
+	}
+	_, err := parseServiceNetworks(&serviceNetworksYAML{
+		Values: map[string]*serviceNetworkYAML{
+			"backend": {Aliases: []string{"DB_INTERNAL"}},
+		},
+	}, networks)
+	if err == nil {
+		t.Fail()
+	}
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Values of Toleration.Operator and Toleration.Effect, see https://kubernetes.io/docs/concepts/configuration/taint-and-toleration/
+const (
+	TolerationOperatorEqual  = "Equal"
+	TolerationOperatorExists = "Exists"
+
+	TaintEffectNoExecute        = "NoExecute"
+	TaintEffectNoSchedule       = "NoSchedule"
+	TaintEffectPreferNoSchedule = "PreferNoSchedule"
+)
+
+// reservedPrefix is the prefix of labels and annotations that are set by kube-compose.
+const reservedPrefix = "kube-compose/"
+
+// ResourcesConfig are the compute resources of the container of a pod, where the keys are resource names (e.g. cpu and memory) and the
+// values are quantities (e.g. 500m and 1Gi).
+type ResourcesConfig struct {
+	Limits   map[string]string `mapdecode:"limits"`
+	Requests map[string]string `mapdecode:"requests"`
+}
+
+// Toleration allows a pod to be scheduled onto nodes with a matching taint.
+type Toleration struct {
+	Effect            string `mapdecode:"effect"`
+	Key               string `mapdecode:"key"`
+	Operator          string `mapdecode:"operator"`
+	TolerationSeconds *int64 `mapdecode:"toleration_seconds"`
+	Value             string `mapdecode:"value"`
+}
+
+// PodConfig customizes the pods of services. The PodConfig of the top-level x-kube-compose applies to all services, and is overridden
+// by the x-kube-compose of each service (see mergePodConfigs).
+type PodConfig struct {
+	Annotations        map[string]string `mapdecode:"annotations"`
+	ImagePullSecrets   []string          `mapdecode:"image_pull_secrets"`
+	Labels             map[string]string `mapdecode:"labels"`
+	NodeSelector       map[string]string `mapdecode:"node_selector"`
+	PriorityClassName  string            `mapdecode:"priority_class_name"`
+	Resources          ResourcesConfig   `mapdecode:"resources"`
+	ServiceAccountName string            `mapdecode:"service_account_name"`
+	Tolerations        []Toleration      `mapdecode:"tolerations"`
+}
+
+// parsePodConfig validates podConfig. The labels app and environmentLabel are reserved, because they select the pods of services.
+func parsePodConfig(podConfig *PodConfig, environmentLabel string) error {
+	for key, value := range podConfig.Labels {
+		if errors := validation.IsQualifiedName(key); len(errors) > 0 {
+			return fmt.Errorf("x-kube-compose.labels has an invalid key %#v: %s", key, errors[0])
+		}
+		if key == "app" || key == environmentLabel || strings.HasPrefix(key, reservedPrefix) {
+			return fmt.Errorf("x-kube-compose.labels has the key %#v, which is reserved by kube-compose", key)
+		}
+		if errors := validation.IsValidLabelValue(value); len(errors) > 0 {
+			return fmt.Errorf("x-kube-compose.labels.%s has an invalid value %#v: %s", key, value, errors[0])
+		}
+	}
+	for key := range podConfig.Annotations {
+		if errors := validation.IsQualifiedName(key); len(errors) > 0 {
+			return fmt.Errorf("x-kube-compose.annotations has an invalid key %#v: %s", key, errors[0])
+		}
+		if strings.HasPrefix(key, reservedPrefix) {
+			return fmt.Errorf("x-kube-compose.annotations has the key %#v, which is reserved by kube-compose", key)
+		}
+	}
+	for key, value := range podConfig.NodeSelector {
+		if errors := validation.IsQualifiedName(key); len(errors) > 0 {
+			return fmt.Errorf("x-kube-compose.node_selector has an invalid key %#v: %s", key, errors[0])
+		}
+		if errors := validation.IsValidLabelValue(value); len(errors) > 0 {
+			return fmt.Errorf("x-kube-compose.node_selector.%s has an invalid value %#v: %s", key, value, errors[0])
+		}
+	}
+	for _, resourceList := range []struct {
+		name   string
+		values map[string]string
+	}{
+		{name: "limits", values: podConfig.Resources.Limits},
+		{name: "requests", values: podConfig.Resources.Requests},
+	} {
+		for name, quantity := range resourceList.values {
+			if _, err := resource.ParseQuantity(quantity); err != nil {
+				return fmt.Errorf("x-kube-compose.resources.%s.%s has an invalid quantity %#v: %v", resourceList.name, name, quantity, err)
+			}
+		}
+	}
+	for i, toleration := range podConfig.Tolerations {
+		switch toleration.Operator {
+		case "", TolerationOperatorEqual:
+		case TolerationOperatorExists:
+			if len(toleration.Value) > 0 {
+				return fmt.Errorf("x-kube-compose.tolerations[%d] has operator %s and a value", i, TolerationOperatorExists)
+			}
+		default:
+			return fmt.Errorf("x-kube-compose.tolerations[%d].operator must be one of %s and %s, but got %#v", i, TolerationOperatorEqual,
+				TolerationOperatorExists, toleration.Operator)
+		}
+		switch toleration.Effect {
+		case "", TaintEffectNoExecute, TaintEffectNoSchedule, TaintEffectPreferNoSchedule:
+		default:
+			return fmt.Errorf("x-kube-compose.tolerations[%d].effect must be one of %s, %s and %s, but got %#v", i, TaintEffectNoExecute,
+				TaintEffectNoSchedule, TaintEffectPreferNoSchedule, toleration.Effect)
+		}
+		if toleration.TolerationSeconds != nil && toleration.Effect != TaintEffectNoExecute {
+			return fmt.Errorf("x-kube-compose.tolerations[%d] has toleration_seconds, but its effect is not %s", i, TaintEffectNoExecute)
+		}
+	}
+	for _, name := range podConfig.ImagePullSecrets {
+		if errors := validation.IsDNS1123Subdomain(name); len(errors) > 0 {
+			return fmt.Errorf("x-kube-compose.image_pull_secrets has an invalid name %#v: %s", name, errors[0])
+		}
+	}
+	if len(podConfig.PriorityClassName) > 0 {
+		if errors := validation.IsDNS1123Subdomain(podConfig.PriorityClassName); len(errors) > 0 {
+			return fmt.Errorf("x-kube-compose.priority_class_name is invalid: %s", errors[0])
+		}
+	}
+	if len(podConfig.ServiceAccountName) > 0 {
+		if errors := validation.IsDNS1123Subdomain(podConfig.ServiceAccountName); len(errors) > 0 {
+			return fmt.Errorf("x-kube-compose.service_account_name is invalid: %s", errors[0])
+		}
+	}
+	return nil
+}
+
+func mergeStringMaps(defaults, overrides map[string]string) map[string]string {
+	if len(defaults) == 0 {
+		return overrides
+	}
+	merged := make(map[string]string, len(defaults)+len(overrides))
+	for key, value := range defaults {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	return merged
+}
+
+// mergePodConfigs returns the PodConfig of a service, where overrides is the PodConfig of the x-kube-compose of the service. Like
+// docker-compose merges files, mappings are merged per key, and lists and other values of overrides replace those of defaults.
+func mergePodConfigs(defaults, overrides *PodConfig) PodConfig {
+	merged := PodConfig{
+		Annotations:        mergeStringMaps(defaults.Annotations, overrides.Annotations),
+		ImagePullSecrets:   defaults.ImagePullSecrets,
+		Labels:             mergeStringMaps(defaults.Labels, overrides.Labels),
+		NodeSelector:       mergeStringMaps(defaults.NodeSelector, overrides.NodeSelector),
+		PriorityClassName:  defaults.PriorityClassName,
+		ServiceAccountName: defaults.ServiceAccountName,
+		Resources: ResourcesConfig{
+			Limits:   mergeStringMaps(defaults.Resources.Limits, overrides.Resources.Limits),
+			Requests: mergeStringMaps(defaults.Resources.Requests, overrides.Resources.Requests),
+		},
+		Tolerations: defaults.Tolerations,
+	}
+	if overrides.ImagePullSecrets != nil {
+		merged.ImagePullSecrets = overrides.ImagePullSecrets
+	}
+	if len(overrides.PriorityClassName) > 0 {
+		merged.PriorityClassName = overrides.PriorityClassName
+	}
+	if len(overrides.ServiceAccountName) > 0 {
+		merged.ServiceAccountName = overrides.ServiceAccountName
+	}
+	if overrides.Tolerations != nil {
+		merged.Tolerations = overrides.Tolerations
+	}
+	return merged
+}
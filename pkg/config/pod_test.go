@@ -0,0 +1,94 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePodConfigValid(t *testing.T) {
+	tolerationSeconds := int64(60)
+	err := parsePodConfig(&PodConfig{
+		Annotations:      map[string]string{"example.com/owner": "team a"},
+		ImagePullSecrets: []string{"registry"},
+		Labels:           map[string]string{"team": "a"},
+		NodeSelector:     map[string]string{"kubernetes.io/os": "linux"},
+		Resources: ResourcesConfig{
+			Limits:   map[string]string{"memory": "1Gi"},
+			Requests: map[string]string{"cpu": "500m"},
+		},
+		ServiceAccountName: "default",
+		Tolerations: []Toleration{
+			{
+				Effect:            TaintEffectNoExecute,
+				Key:               "dedicated",
+				Operator:          TolerationOperatorExists,
+				TolerationSeconds: &tolerationSeconds,
+			},
+		},
+	}, "env")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParsePodConfigReservedLabel(t *testing.T) {
+	for _, key := range []string{"app", "env", "kube-compose/network-default"} {
+		err := parsePodConfig(&PodConfig{
+			Labels: map[string]string{key: "x"},
+		}, "env")
+		if err == nil {
+			t.Fatal(key)
+		}
+	}
+}
+
+func TestParsePodConfigInvalidQuantity(t *testing.T) {
+	err := parsePodConfig(&PodConfig{
+		Resources: ResourcesConfig{
+			Requests: map[string]string{"memory": "lots"},
+		},
+	}, "env")
+	if err == nil {
+		t.Fail()
+	}
+}
+
+func TestParsePodConfigInvalidToleration(t *testing.T) {
+	tolerationSeconds := int64(60)
+	for _, toleration := range []Toleration{
+		{Operator: "In"},
+		{Effect: "NoRun"},
+		{Operator: TolerationOperatorExists, Value: "x"},
+		{Effect: TaintEffectNoSchedule, TolerationSeconds: &tolerationSeconds},
+	} {
+		err := parsePodConfig(&PodConfig{
+			Tolerations: []Toleration{toleration},
+		}, "env")
+		if err == nil {
+			t.Fatalf("%#v", toleration)
+		}
+	}
+}
+
+func TestMergePodConfigs(t *testing.T) {
+	defaults := &PodConfig{
+		Labels:            map[string]string{"team": "a", "tier": "backend"},
+		NodeSelector:      map[string]string{"pool": "default"},
+		PriorityClassName: "low",
+		Tolerations:       []Toleration{{Key: "a"}},
+	}
+	overrides := &PodConfig{
+		Labels:      map[string]string{"tier": "frontend"},
+		Tolerations: []Toleration{{Key: "b"}},
+	}
+	merged := mergePodConfigs(defaults, overrides)
+	expected := PodConfig{
+		Labels:            map[string]string{"team": "a", "tier": "frontend"},
+		NodeSelector:      map[string]string{"pool": "default"},
+		PriorityClassName: "low",
+		Tolerations:       []Toleration{{Key: "b"}},
+	}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Fatalf("%#v", merged)
+	}
+}
@@ -0,0 +1,278 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	ProbeSchemeHTTP  = "HTTP"
+	ProbeSchemeHTTPS = "HTTPS"
+)
+
+// HTTPGetProbe is a probe that succeeds if a HTTP GET request to the pod returns a status code in the range [200, 400).
+type HTTPGetProbe struct {
+	Path   string `mapdecode:"path"`
+	Port   int32  `mapdecode:"port"`
+	Scheme string `mapdecode:"scheme"`
+}
+
+// TCPSocketProbe is a probe that succeeds if a TCP connection to the pod can be opened.
+type TCPSocketProbe struct {
+	Port int32 `mapdecode:"port"`
+}
+
+// Probe replaces the command of the probes that are derived from a docker healthcheck. Exactly one of its fields is set. The interval,
+// timeout and retries of probes are still taken from the healthcheck.
+type Probe struct {
+	HTTPGet   *HTTPGetProbe   `mapdecode:"http_get"`
+	TCPSocket *TCPSocketProbe `mapdecode:"tcp_socket"`
+}
+
+func isValidPort(port int32) bool {
+	return 0 < port && port <= 65535
+}
+
+// parseProbe validates the probe declared in the x-kube-compose of a service, and sets default values.
+func parseProbe(probeYAML *Probe) (*Probe, error) {
+	if probeYAML == nil {
+		return nil, nil
+	}
+	if (probeYAML.HTTPGet == nil) == (probeYAML.TCPSocket == nil) {
+		return nil, fmt.Errorf("x-kube-compose.probe must have exactly one of http_get and tcp_socket")
+	}
+	if probeYAML.TCPSocket != nil {
+		if !isValidPort(probeYAML.TCPSocket.Port) {
+			return nil, fmt.Errorf("x-kube-compose.probe.tcp_socket has an invalid port: %d", probeYAML.TCPSocket.Port)
+		}
+		return probeYAML, nil
+	}
+	httpGet := *probeYAML.HTTPGet
+	if !isValidPort(httpGet.Port) {
+		return nil, fmt.Errorf("x-kube-compose.probe.http_get has an invalid port: %d", httpGet.Port)
+	}
+	if len(httpGet.Path) == 0 {
+		httpGet.Path = "/"
+	} else if !strings.HasPrefix(httpGet.Path, "/") {
+		return nil, fmt.Errorf("x-kube-compose.probe.http_get has a path that does not start with a slash: %#v", httpGet.Path)
+	}
+	switch strings.ToUpper(httpGet.Scheme) {
+	case "", ProbeSchemeHTTP:
+		httpGet.Scheme = ProbeSchemeHTTP
+	case ProbeSchemeHTTPS:
+		httpGet.Scheme = ProbeSchemeHTTPS
+	default:
+		return nil, fmt.Errorf("x-kube-compose.probe.http_get.scheme must be one of %s and %s, but got %#v", ProbeSchemeHTTP, ProbeSchemeHTTPS,
+			httpGet.Scheme)
+	}
+	return &Probe{
+		HTTPGet: &httpGet,
+	}, nil
+}
+
+// TranslateHealthcheck recognizes healthchecks of the forms "curl -f URL", "wget -q --spider URL" and "nc -z HOST PORT" (optionally
+// followed by "|| exit 1"), and returns the equivalent HTTP GET or TCP socket probe. This way images without a shell, curl, wget or
+// nc can be probed. Only healthchecks of localhost are translated, and nil is returned if the healthcheck is not recognized.
+func TranslateHealthcheck(healthcheck *Healthcheck) *Probe {
+	words := healthcheck.Test
+	if healthcheck.IsShell {
+		if len(words) != 1 {
+			return nil
+		}
+		var err error
+		words, err = splitShellWords(words[0])
+		if err != nil {
+			return nil
+		}
+		words = trimExitOnFailure(words)
+		for _, word := range words {
+			// Pipes, redirects, variables etc. make the healthcheck more than a single command.
+			if strings.ContainsAny(word, ";&|<>$`()") {
+				return nil
+			}
+		}
+	}
+	if len(words) == 0 {
+		return nil
+	}
+	switch filepath.Base(words[0]) {
+	case "curl":
+		return translateCurl(words[1:])
+	case "wget":
+		return translateWget(words[1:])
+	case "nc":
+		return translateNetcat(words[1:])
+	}
+	return nil
+}
+
+// trimExitOnFailure removes the suffix "|| exit N" that is common in healthchecks, because exec probes fail if the exit code is not 0
+// anyway.
+func trimExitOnFailure(words []string) []string {
+	n := len(words)
+	if n >= 3 && words[n-3] == "||" && words[n-2] == "exit" {
+		if exitCode, err := strconv.Atoi(words[n-1]); err == nil && exitCode != 0 {
+			return words[:n-3]
+		}
+	}
+	return words
+}
+
+func translateCurl(args []string) *Probe {
+	fail := false
+	rawURL := ""
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--fail":
+			fail = true
+		case arg == "--silent" || arg == "--show-error" || arg == "--location":
+		case arg == "-o" || arg == "--output":
+			// Only discarding the response body does not change the outcome of the healthcheck.
+			i++
+			if i == len(args) || args[i] != "/dev/null" {
+				return nil
+			}
+		case strings.HasPrefix(arg, "-") && len(arg) > 1 && !strings.HasPrefix(arg, "--"):
+			for _, flag := range arg[1:] {
+				switch flag {
+				case 'f':
+					fail = true
+				case 's', 'S', 'L':
+				default:
+					return nil
+				}
+			}
+		case strings.HasPrefix(arg, "-"):
+			return nil
+		default:
+			if len(rawURL) > 0 {
+				return nil
+			}
+			rawURL = arg
+		}
+	}
+	// Without --fail curl succeeds on any HTTP response, but HTTP GET probes fail on status codes of 400 and above.
+	if !fail || len(rawURL) == 0 {
+		return nil
+	}
+	return translateURL(rawURL)
+}
+
+func translateWget(args []string) *Probe {
+	rawURL := ""
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-q", "--quiet", "--spider", "-qO-", "-O-", "--no-verbose":
+		case "-O":
+			i++
+			if i == len(args) || (args[i] != "-" && args[i] != "/dev/null") {
+				return nil
+			}
+		default:
+			if strings.HasPrefix(arg, "-") || len(rawURL) > 0 {
+				return nil
+			}
+			rawURL = arg
+		}
+	}
+	// wget fails on status codes of 400 and above by default, like HTTP GET probes.
+	if len(rawURL) == 0 {
+		return nil
+	}
+	return translateURL(rawURL)
+}
+
+func translateNetcat(args []string) *Probe {
+	zero := false
+	operands := []string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-z", "-vz", "-zv":
+			zero = true
+		case "-v":
+		case "-w":
+			// The timeout of the probe takes the role of the timeout of nc.
+			i++
+			if i == len(args) {
+				return nil
+			}
+		default:
+			if strings.HasPrefix(arg, "-") {
+				return nil
+			}
+			operands = append(operands, arg)
+		}
+	}
+	if !zero || len(operands) != 2 || !isLocalhost(operands[0]) {
+		return nil
+	}
+	port, err := strconv.ParseInt(operands[1], 10, 32)
+	if err != nil || !isValidPort(int32(port)) {
+		return nil
+	}
+	return &Probe{
+		TCPSocket: &TCPSocketProbe{
+			Port: int32(port),
+		},
+	}
+}
+
+func translateURL(rawURL string) *Probe {
+	if !strings.Contains(rawURL, "://") {
+		// Like curl and wget, default to http.
+		rawURL = "http://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User != nil || len(u.Fragment) > 0 {
+		return nil
+	}
+	httpGet := &HTTPGetProbe{}
+	var defaultPort int32
+	switch u.Scheme {
+	case "http":
+		httpGet.Scheme = ProbeSchemeHTTP
+		defaultPort = 80
+	case "https":
+		httpGet.Scheme = ProbeSchemeHTTPS
+		defaultPort = 443
+	default:
+		return nil
+	}
+	if !isLocalhost(u.Hostname()) {
+		return nil
+	}
+	httpGet.Port = defaultPort
+	if portString := u.Port(); len(portString) > 0 {
+		port, err := strconv.ParseInt(portString, 10, 32)
+		if err != nil || !isValidPort(int32(port)) {
+			return nil
+		}
+		httpGet.Port = int32(port)
+	}
+	httpGet.Path = u.EscapedPath()
+	if len(httpGet.Path) == 0 {
+		httpGet.Path = "/"
+	}
+	if len(u.RawQuery) > 0 {
+		httpGet.Path += "?" + u.RawQuery
+	}
+	return &Probe{
+		HTTPGet: httpGet,
+	}
+}
+
+// isLocalhost returns true if host refers to the container itself. Probes connect to the IP of the pod instead.
+func isLocalhost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsUnspecified())
+}
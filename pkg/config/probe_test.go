@@ -0,0 +1,114 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTranslateHealthcheckCurl(t *testing.T) {
+	probe := TranslateHealthcheck(&Healthcheck{
+		IsShell: true,
+		Test:    []string{"curl -fsS http://localhost:8080/health?verbose=1 || exit 1"},
+	})
+	expected := &Probe{
+		HTTPGet: &HTTPGetProbe{
+			Path:   "/health?verbose=1",
+			Port:   8080,
+			Scheme: ProbeSchemeHTTP,
+		},
+	}
+	if !reflect.DeepEqual(probe, expected) {
+		t.Fatalf("%#v", probe)
+	}
+}
+
+func TestTranslateHealthcheckCurlWithoutFail(t *testing.T) {
+	probe := TranslateHealthcheck(&Healthcheck{
+		Test: []string{"curl", "http://localhost:8080/health"},
+	})
+	if probe != nil {
+		t.Fatalf("%#v", probe)
+	}
+}
+
+func TestTranslateHealthcheckWget(t *testing.T) {
+	probe := TranslateHealthcheck(&Healthcheck{
+		Test: []string{"/usr/bin/wget", "-q", "--spider", "https://127.0.0.1/"},
+	})
+	expected := &Probe{
+		HTTPGet: &HTTPGetProbe{
+			Path:   "/",
+			Port:   443,
+			Scheme: ProbeSchemeHTTPS,
+		},
+	}
+	if !reflect.DeepEqual(probe, expected) {
+		t.Fatalf("%#v", probe)
+	}
+}
+
+func TestTranslateHealthcheckNetcat(t *testing.T) {
+	probe := TranslateHealthcheck(&Healthcheck{
+		IsShell: true,
+		Test:    []string{"nc -z localhost 5432"},
+	})
+	expected := &Probe{
+		TCPSocket: &TCPSocketProbe{
+			Port: 5432,
+		},
+	}
+	if !reflect.DeepEqual(probe, expected) {
+		t.Fatalf("%#v", probe)
+	}
+}
+
+func TestTranslateHealthcheckOtherHost(t *testing.T) {
+	probe := TranslateHealthcheck(&Healthcheck{
+		IsShell: true,
+		Test:    []string{"nc -z db 5432"},
+	})
+	if probe != nil {
+		t.Fatalf("%#v", probe)
+	}
+}
+
+func TestTranslateHealthcheckPipe(t *testing.T) {
+	probe := TranslateHealthcheck(&Healthcheck{
+		IsShell: true,
+		Test:    []string{"curl -f http://localhost/ | grep ok"},
+	})
+	if probe != nil {
+		t.Fatalf("%#v", probe)
+	}
+}
+
+func TestParseProbeHTTPGetDefaults(t *testing.T) {
+	probe, err := parseProbe(&Probe{
+		HTTPGet: &HTTPGetProbe{
+			Port:   8080,
+			Scheme: "https",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if probe.HTTPGet.Path != "/" || probe.HTTPGet.Scheme != ProbeSchemeHTTPS {
+		t.Fatalf("%#v", probe.HTTPGet)
+	}
+}
+
+func TestParseProbeInvalid(t *testing.T) {
+	_, err := parseProbe(&Probe{
+		HTTPGet:   &HTTPGetProbe{Port: 8080},
+		TCPSocket: &TCPSocketProbe{Port: 8080},
+	})
+	if err == nil {
+		t.Fail()
+	}
+	_, err = parseProbe(&Probe{
+		TCPSocket: &TCPSocketProbe{Port: 0},
+	})
+	if err == nil {
+		t.Fail()
+	}
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/uber-go/mapdecode"
+)
+
+// Values of restart, the restart policy of the containers of a service.
+const (
+	RestartAlways        = "always"
+	RestartNo            = "no"
+	RestartOnFailure     = "on-failure"
+	RestartUnlessStopped = "unless-stopped"
+)
+
+// restartYAML decodes restart, which may also be the boolean false because YAML 1.1 parses an unquoted no as a boolean.
+type restartYAML struct {
+	Value string
+}
+
+func (r *restartYAML) Decode(into mapdecode.Into) error {
+	var b bool
+	err := into(&b)
+	if err == nil {
+		if b {
+			return fmt.Errorf("restart must be one of %s, %s, %s and %s, but got true", RestartNo, RestartAlways, RestartOnFailure,
+				RestartUnlessStopped)
+		}
+		r.Value = RestartNo
+		return nil
+	}
+	return into(&r.Value)
+}
+
+// parseRestart returns the restart policy of a service of kind kind, and the maximum number of restarts of on-failure[:max-retries] (0
+// if unlimited). By default containers are not restarted, except those of Deployments and StatefulSets.
+func parseRestart(value, kind string) (string, int, error) {
+	restart := value
+	maxRetries := 0
+	if i := strings.IndexRune(value, ':'); i >= 0 && value[:i] == RestartOnFailure {
+		restart = RestartOnFailure
+		var err error
+		maxRetries, err = strconv.Atoi(value[i+1:])
+		if err != nil || maxRetries < 0 {
+			return "", 0, fmt.Errorf("restart %#v must have a non-negative number of retries", value)
+		}
+	}
+	switch restart {
+	case "":
+		if kind == KindDeployment || kind == KindStatefulSet {
+			return RestartAlways, 0, nil
+		}
+		return RestartNo, 0, nil
+	case RestartAlways, RestartUnlessStopped:
+		if kind == KindJob {
+			return "", 0, fmt.Errorf("restart %s is not supported by services of kind %s", restart, kind)
+		}
+	case RestartNo, RestartOnFailure:
+		if kind == KindDeployment || kind == KindStatefulSet {
+			return "", 0, fmt.Errorf("restart %s is not supported by services of kind %s", restart, kind)
+		}
+	default:
+		return "", 0, fmt.Errorf("restart must be one of %s, %s, %s[:max-retries] and %s, but got %#v", RestartNo, RestartAlways,
+			RestartOnFailure, RestartUnlessStopped, value)
+	}
+	return restart, maxRetries, nil
+}
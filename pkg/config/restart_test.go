@@ -0,0 +1,52 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestParseRestartDefault(t *testing.T) {
+	restart, _, err := parseRestart("", KindPod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restart != RestartNo {
+		t.Fatal(restart)
+	}
+	restart, _, err = parseRestart("", KindDeployment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restart != RestartAlways {
+		t.Fatal(restart)
+	}
+}
+
+func TestParseRestartOnFailureMaxRetries(t *testing.T) {
+	restart, maxRetries, err := parseRestart("on-failure:3", KindPod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restart != RestartOnFailure || maxRetries != 3 {
+		t.Fatal(restart, maxRetries)
+	}
+}
+
+func TestParseRestartInvalid(t *testing.T) {
+	for _, value := range []string{"sometimes", "on-failure:x", "on-failure:-1"} {
+		_, _, err := parseRestart(value, KindPod)
+		if err == nil {
+			t.Fatal(value)
+		}
+	}
+}
+
+func TestParseRestartUnsupportedByKind(t *testing.T) {
+	_, _, err := parseRestart(RestartAlways, KindJob)
+	if err == nil {
+		t.Fail()
+	}
+	_, _, err = parseRestart(RestartNo, KindStatefulSet)
+	if err == nil {
+		t.Fail()
+	}
+}
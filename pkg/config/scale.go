@@ -0,0 +1,40 @@
+package config
+
+import (
+	"fmt"
+)
+
+// Values of x-kube-compose.replicas_ready, which determine when a service with multiple replicas meets the conditions of depends_on.
+const (
+	ReplicasReadyAll = "all" // the conditions must be met by all replicas.
+	ReplicasReadyOne = "one" // the conditions must be met by at least one replica.
+)
+
+type serviceDeployYAML struct {
+	Replicas *int `mapdecode:"replicas"`
+}
+
+// parseReplicas returns the number of pods of a service, which is set with scale (2.x) or deploy.replicas (3.x).
+func parseReplicas(serviceYAML *service2_1) (int, error) {
+	replicas := 1
+	if serviceYAML.Scale != nil {
+		replicas = *serviceYAML.Scale
+	} else if serviceYAML.Deploy.Replicas != nil {
+		replicas = *serviceYAML.Deploy.Replicas
+	}
+	if replicas < 1 {
+		return 0, fmt.Errorf("must have at least 1 replica, but got %d", replicas)
+	}
+	return replicas, nil
+}
+
+// parseReplicasReady validates value, and returns defaultValue if value is empty.
+func parseReplicasReady(value, defaultValue string) (string, error) {
+	switch value {
+	case "":
+		return defaultValue, nil
+	case ReplicasReadyAll, ReplicasReadyOne:
+		return value, nil
+	}
+	return "", fmt.Errorf("x-kube-compose.replicas_ready must be one of %s and %s, but got %#v", ReplicasReadyAll, ReplicasReadyOne, value)
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestParseReplicasDefault(t *testing.T) {
+	replicas, err := parseReplicas(&service2_1{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replicas != 1 {
+		t.Fatal(replicas)
+	}
+}
+
+func TestParseReplicasScale(t *testing.T) {
+	scale := 3
+	replicas, err := parseReplicas(&service2_1{
+		Scale: &scale,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replicas != 3 {
+		t.Fatal(replicas)
+	}
+}
+
+func TestParseReplicasDeploy(t *testing.T) {
+	deployReplicas := 2
+	replicas, err := parseReplicas(&service2_1{
+		Deploy: serviceDeployYAML{
+			Replicas: &deployReplicas,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replicas != 2 {
+		t.Fatal(replicas)
+	}
+}
+
+func TestParseReplicasZero(t *testing.T) {
+	scale := 0
+	_, err := parseReplicas(&service2_1{
+		Scale: &scale,
+	})
+	if err == nil {
+		t.Fail()
+	}
+}
+
+func TestParseReplicasReady(t *testing.T) {
+	replicasReady, err := parseReplicasReady("", ReplicasReadyOne)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replicasReady != ReplicasReadyOne {
+		t.Fatal(replicasReady)
+	}
+	_, err = parseReplicasReady("any", ReplicasReadyAll)
+	if err == nil {
+		t.Fail()
+	}
+}
@@ -15,10 +15,14 @@ type ServiceHealthiness int
 const (
 	ServiceStarted ServiceHealthiness = 0
 	ServiceHealthy ServiceHealthiness = 1
+	// ServiceCompletedSuccessfully is the condition of a dependency on a service that runs to completion, like a migration.
+	ServiceCompletedSuccessfully ServiceHealthiness = 2
 )
 
 func (s *ServiceHealthiness) String() string {
 	switch *s {
+	case ServiceCompletedSuccessfully:
+		return "serviceCompletedSuccessfully"
 	case ServiceHealthy:
 		return "serviceHealthy"
 	case ServiceStarted:
@@ -44,6 +48,24 @@ func (t *stringOrStringSlice) Decode(into mapdecode.Into) error {
 	return nil
 }
 
+// shellCommand decodes a list of strings, or a string that is split into words like docker-compose does.
+type shellCommand struct {
+	Values []string
+}
+
+func (t *shellCommand) Decode(into mapdecode.Into) error {
+	err := into(&t.Values)
+	if err != nil {
+		var str string
+		err = into(&str)
+		if err != nil {
+			return err
+		}
+		t.Values, err = splitShellWords(str)
+	}
+	return err
+}
+
 type HealthcheckTest struct {
 	Values []string
 }
@@ -64,13 +86,22 @@ func (t *HealthcheckTest) Decode(into mapdecode.Into) error {
 	return nil
 }
 
+// ServiceBuildYAML is the mapping syntax of build. The string syntax is normalized to the mapping syntax while loading files.
+type ServiceBuildYAML struct {
+	Args       environment `mapdecode:"args"`
+	CacheFrom  []string    `mapdecode:"cache_from"`
+	Context    string      `mapdecode:"context"`
+	Dockerfile string      `mapdecode:"dockerfile"`
+	Target     string      `mapdecode:"target"`
+}
+
 type ServiceHealthcheck struct {
-	Disable  bool            `mapdecode:"disable"`
-	Interval *string         `mapdecode:"interval"`
-	Retries  *uint           `mapdecode:"retries"`
-	Test     HealthcheckTest `mapdecode:"test"`
-	Timeout  *string         `mapdecode:"timeout"`
-	// start_period is only available in docker-compose 2.3 or higher
+	Disable     bool            `mapdecode:"disable"`
+	Interval    *string         `mapdecode:"interval"`
+	Retries     *uint           `mapdecode:"retries"`
+	StartPeriod *string         `mapdecode:"start_period"` // only available in docker compose file format 2.3+ and 3.4+
+	Test        HealthcheckTest `mapdecode:"test"`
+	Timeout     *string         `mapdecode:"timeout"`
 }
 
 func (h *ServiceHealthcheck) GetTest() []string {
@@ -105,6 +136,8 @@ func (t *dependsOn) Decode(into mapdecode.Into) error {
 		t.Values = make(map[string]ServiceHealthiness, n)
 		for service, obj := range strMap {
 			switch obj.Condition {
+			case "service_completed_successfully":
+				t.Values[service] = ServiceCompletedSuccessfully
 			case "service_healthy":
 				t.Values[service] = ServiceHealthy
 			case "service_started":
@@ -152,6 +185,20 @@ func (v *environmentValue) Decode(into mapdecode.Into) error {
 	return err
 }
 
+// stringValue returns the value as a string, or false if the value is null.
+func (v *environmentValue) stringValue() (string, bool) {
+	if v.StringValue != nil {
+		return *v.StringValue, true
+	}
+	if v.IntValue != nil {
+		return strconv.Itoa(*v.IntValue), true
+	}
+	if v.FloatValue != nil {
+		return strconv.FormatFloat(*v.FloatValue, 'g', -1, 64), true
+	}
+	return "", false
+}
+
 type environment struct {
 	Values []environmentNameValuePair
 }
@@ -208,22 +255,50 @@ func (p *port) Decode(into mapdecode.Into) error {
 	return err
 }
 
+// serviceCustomYAML is the x-kube-compose of a service.
+type serviceCustomYAML struct {
+	PodConfig `mapdecode:",squash"`
+
+	Kind          string `mapdecode:"kind"`
+	Probe         *Probe `mapdecode:"probe"`
+	ReplicasReady string `mapdecode:"replicas_ready"`
+}
+
+// Decode rejects unknown keys, because services are decoded ignoring unused keys (e.g. unsupported docker compose features) but a typo
+// in x-kube-compose should not go unnoticed.
+func (t *serviceCustomYAML) Decode(into mapdecode.Into) error {
+	var raw interface{}
+	err := into(&raw)
+	if err != nil {
+		return err
+	}
+	type plain serviceCustomYAML
+	return mapdecode.Decode((*plain)(t), raw)
+}
+
+// service2_1 and composeFile2_1 are used to decode files of all supported versions (2.x and 3.x). Fields that are inappropriate for the
+// version of a file are rejected by validateVersion before decoding.
 type service2_1 struct {
-	Build struct {
-		Context    string `mapdecode:"context"`
-		Dockerfile string `mapdecode:"dockerfile"`
-	} `mapdecode:"build"`
+	Build       *ServiceBuildYAML   `mapdecode:"build"`
+	Command     shellCommand        `mapdecode:"command"`
 	DependsOn   dependsOn           `mapdecode:"depends_on"`
-	Entrypoint  stringOrStringSlice `mapdecode:"entrypoint"`
+	Deploy      serviceDeployYAML   `mapdecode:"deploy"`
+	Entrypoint  shellCommand        `mapdecode:"entrypoint"`
+	EnvFile     stringOrStringSlice `mapdecode:"env_file"`
 	Environment environment         `mapdecode:"environment"`
 	Healthcheck *ServiceHealthcheck `mapdecode:"healthcheck"`
 	Image       string              `mapdecode:"image"`
+	Networks    serviceNetworksYAML `mapdecode:"networks"`
 	Ports       []port              `mapdecode:"ports"`
-	Volumes     []string            `mapdecode:"volumes"`
+	Restart     restartYAML         `mapdecode:"restart"`
+	Scale       *int                `mapdecode:"scale"`
+	Volumes     []serviceVolumeYAML `mapdecode:"volumes"`
 	WorkingDir  string              `mapdecode:"working_dir"`
+	Custom      serviceCustomYAML   `mapdecode:"x-kube-compose"`
 }
 
 type composeFile2_1 struct {
-	Services map[string]service2_1  `mapdecode:"services"`
-	Volumes  map[string]interface{} `mapdecode:"volumes"`
+	Networks map[string]*networkYAML `mapdecode:"networks"`
+	Services map[string]service2_1   `mapdecode:"services"`
+	Volumes  map[string]*volumeYAML  `mapdecode:"volumes"`
 }
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/uber-go/mapdecode"
+)
+
+func TestDependsOnDecodeList(t *testing.T) {
+	var d dependsOn
+	err := mapdecode.Decode(&d, []interface{}{"db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Values) != 1 || d.Values["db"] != ServiceStarted {
+		t.Fatalf("%#v", d.Values)
+	}
+}
+
+func TestDependsOnDecodeConditions(t *testing.T) {
+	var d dependsOn
+	err := mapdecode.Decode(&d, map[interface{}]interface{}{
+		"db": map[interface{}]interface{}{
+			"condition": "service_healthy",
+		},
+		"migrations": map[interface{}]interface{}{
+			"condition": "service_completed_successfully",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Values) != 2 || d.Values["db"] != ServiceHealthy || d.Values["migrations"] != ServiceCompletedSuccessfully {
+		t.Fatalf("%#v", d.Values)
+	}
+}
+
+func TestDependsOnDecodeInvalidCondition(t *testing.T) {
+	var d dependsOn
+	err := mapdecode.Decode(&d, map[interface{}]interface{}{
+		"db": map[interface{}]interface{}{
+			"condition": "service_running",
+		},
+	})
+	if err == nil {
+		t.Fail()
+	}
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// splitShellWords splits str into words using the same rules as Python's shlex.split in POSIX mode, which is what docker-compose uses
+// to split the string forms of command and entrypoint.
+// https://docs.python.org/3/library/shlex.html#parsing-rules
+func splitShellWords(str string) ([]string, error) {
+	words := []string{}
+	var word strings.Builder
+	inWord := false
+	n := len(str)
+	for i := 0; i < n; i++ {
+		c := str[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case c == '\\':
+			inWord = true
+			i++
+			if i == n {
+				return nil, fmt.Errorf("no escaped character in %#v", str)
+			}
+			word.WriteByte(str[i])
+		case c == '\'':
+			inWord = true
+			j := strings.IndexByte(str[i+1:], '\'')
+			if j < 0 {
+				return nil, fmt.Errorf("no closing quotation in %#v", str)
+			}
+			word.WriteString(str[i+1 : i+1+j])
+			i += j + 1
+		case c == '"':
+			inWord = true
+			closed := false
+			for i++; i < n; i++ {
+				c = str[i]
+				if c == '"' {
+					closed = true
+					break
+				}
+				// Within double quotes a backslash only escapes a double quote or a backslash.
+				if c == '\\' && i+1 < n && (str[i+1] == '"' || str[i+1] == '\\') {
+					i++
+					c = str[i]
+				}
+				word.WriteByte(c)
+			}
+			if !closed {
+				return nil, fmt.Errorf("no closing quotation in %#v", str)
+			}
+		default:
+			inWord = true
+			word.WriteByte(c)
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitShellWordsSimple(t *testing.T) {
+	words, err := splitShellWords("  bundle exec  thin -p 3000 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"bundle", "exec", "thin", "-p", "3000"}
+	if !reflect.DeepEqual(words, expected) {
+		t.Fatal(words)
+	}
+}
+
+func TestSplitShellWordsQuotes(t *testing.T) {
+	words, err := splitShellWords(`/bin/sh -c 'echo "$$HOME"' "a \"b\" \c" d\ e ""`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"/bin/sh", "-c", `echo "$$HOME"`, `a "b" \c`, "d e", ""}
+	if !reflect.DeepEqual(words, expected) {
+		t.Fatalf("%#v", words)
+	}
+}
+
+func TestSplitShellWordsNoClosingQuotation(t *testing.T) {
+	for _, str := range []string{"'a", "\"a", "a\\"} {
+		if _, err := splitShellWords(str); err == nil {
+			t.Fatal(str)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	version "github.com/hashicorp/go-version"
+)
+
+// serviceFieldVersion describes the minimum 2.x and 3.x versions of the docker compose file format that support a field of a service.
+// A nil minimum version means the field is not supported by any version with that major version.
+type serviceFieldVersion struct {
+	path        []string
+	minVersion2 *version.Version
+	minVersion3 *version.Version
+}
+
+// https://docs.docker.com/compose/compose-file/compose-versioning/
+var serviceFieldVersions = []serviceFieldVersion{
+	{path: []string{"build", "cache_from"}, minVersion2: v2_2, minVersion3: v3_2},
+	{path: []string{"build", "network"}, minVersion2: v2_2, minVersion3: v3_4},
+	{path: []string{"build", "target"}, minVersion2: v2_3, minVersion3: v3_4},
+	{path: []string{"cpu_quota"}, minVersion2: v2},
+	{path: []string{"cpu_shares"}, minVersion2: v2},
+	{path: []string{"cpuset"}, minVersion2: v2},
+	{path: []string{"deploy"}, minVersion3: v3},
+	{path: []string{"extends"}, minVersion2: v2},
+	{path: []string{"healthcheck"}, minVersion2: v2_1, minVersion3: v3},
+	{path: []string{"healthcheck", "start_period"}, minVersion2: v2_3, minVersion3: v3_4},
+	{path: []string{"init"}, minVersion2: v2_2, minVersion3: v3_7},
+	{path: []string{"mem_limit"}, minVersion2: v2},
+	{path: []string{"memswap_limit"}, minVersion2: v2},
+	{path: []string{"runtime"}, minVersion2: v2_3},
+	{path: []string{"scale"}, minVersion2: v2_2},
+	{path: []string{"volume_driver"}, minVersion2: v2},
+	{path: []string{"volumes_from"}, minVersion2: v2},
+}
+
+// validateVersion checks that the version of the docker compose file format is supported, and rejects fields of services that are
+// inappropriate for that version (like docker-compose does). The services are decoded into the same structures for all supported
+// versions, so this is what distinguishes 2.x from 3.x.
+func validateVersion(ver *version.Version, dataMap genericMap) error {
+	if ver.LessThan(v2) || !ver.LessThan(v4) {
+		return fmt.Errorf("version %s of the docker compose file format is not supported, please use a 2.x or 3.x version", ver)
+	}
+	isVersion3 := !ver.LessThan(v3)
+	services, err := asMap(dataMap["services"], "services")
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(services))
+	for nameRaw := range services {
+		if name, ok := nameRaw.(string); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		service, err := asMap(services[name], fmt.Sprintf("service %s", name))
+		if err != nil {
+			return err
+		}
+		for _, fieldVersion := range serviceFieldVersions {
+			if !hasPath(service, fieldVersion.path) {
+				continue
+			}
+			minVersion := fieldVersion.minVersion2
+			if isVersion3 {
+				minVersion = fieldVersion.minVersion3
+			}
+			if minVersion == nil || ver.LessThan(minVersion) {
+				return fmt.Errorf("service %s has field %s, which is not supported by version %s of the docker compose file format",
+					name, strings.Join(fieldVersion.path, "."), ver)
+			}
+		}
+		if isVersion3 {
+			if _, ok := service["depends_on"].([]interface{}); !ok && service["depends_on"] != nil {
+				return fmt.Errorf("service %s has a depends_on that is not a list, but conditions are not supported by version %s of the docker compose file format", name, ver)
+			}
+		}
+	}
+	return nil
+}
+
+func hasPath(m map[interface{}]interface{}, path []string) bool {
+	for i, key := range path {
+		value, ok := m[key]
+		if !ok {
+			return false
+		}
+		if i+1 < len(path) {
+			if m, ok = value.(map[interface{}]interface{}); !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	version "github.com/hashicorp/go-version"
+)
+
+func newServicesDataMap(name string, service map[interface{}]interface{}) genericMap {
+	return genericMap{
+		"services": map[interface{}]interface{}{
+			name: service,
+		},
+	}
+}
+
+func TestValidateVersionUnsupported(t *testing.T) {
+	for _, verStr := range []string{"1", "4.0"} {
+		err := validateVersion(version.Must(version.NewVersion(verStr)), genericMap{})
+		if err == nil {
+			t.Fatal(verStr)
+		}
+	}
+}
+
+func TestValidateVersion3DependsOnList(t *testing.T) {
+	dataMap := newServicesDataMap("a", map[interface{}]interface{}{
+		"depends_on": []interface{}{
+			"b",
+		},
+	})
+	err := validateVersion(v3_7, dataMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateVersion3DependsOnCondition(t *testing.T) {
+	dataMap := newServicesDataMap("a", map[interface{}]interface{}{
+		"depends_on": map[interface{}]interface{}{
+			"b": map[interface{}]interface{}{
+				"condition": "service_healthy",
+			},
+		},
+	})
+	err := validateVersion(v3_7, dataMap)
+	if err == nil {
+		t.Fail()
+	}
+}
+
+func TestValidateVersionStartPeriod(t *testing.T) {
+	dataMap := newServicesDataMap("a", map[interface{}]interface{}{
+		"healthcheck": map[interface{}]interface{}{
+			"start_period": "10s",
+		},
+	})
+	for _, ver := range []*version.Version{v2_3, v3_4, v3_7} {
+		if err := validateVersion(ver, dataMap); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, ver := range []*version.Version{v2_1, v3_2} {
+		if err := validateVersion(ver, dataMap); err == nil {
+			t.Fatal(ver)
+		}
+	}
+}
+
+func TestValidateVersionFieldNotInVersion3(t *testing.T) {
+	dataMap := newServicesDataMap("a", map[interface{}]interface{}{
+		"extends": "b",
+	})
+	err := validateVersion(v3_7, dataMap)
+	if err == nil {
+		t.Fail()
+	}
+}
+
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "kube-compose-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		if err != nil {
+			os.RemoveAll(dir)
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestNewVersion3DependsOnListInOverride(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"docker-compose.yml":          "version: '3.4'\nservices:\n  a:\n    image: a\n    depends_on: [b]\n  b:\n    image: b\n  c:\n    image: c\n",
+		"docker-compose.override.yml": "version: '3.4'\nservices:\n  a:\n    depends_on: [c]\n",
+	})
+	defer os.RemoveAll(dir)
+	cfg, err := New([]string{filepath.Join(dir, "docker-compose.yml"), filepath.Join(dir, "docker-compose.override.yml")}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.CanonicalComposeFile.Services["a"].DependsOn) != 2 {
+		t.Fatal(cfg.CanonicalComposeFile.Services["a"].DependsOn)
+	}
+}
+
+func TestNewExtendsFileValidated(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"docker-compose.yml": "version: '2.4'\nservices:\n  a:\n    extends:\n      file: common.yml\n      service: base\n",
+		"common.yml":         "version: '2'\nservices:\n  base:\n    image: a\n    scale: 2\n",
+	})
+	defer os.RemoveAll(dir)
+	_, err := New([]string{filepath.Join(dir, "docker-compose.yml")}, "")
+	if err == nil {
+		t.Fail()
+	}
+}
+
+func TestNewVersionMissing(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"docker-compose.yml": "services:\n  a:\n    image: a\n    depends_on: [b]\n  b:\n    image: b\n",
+	})
+	defer os.RemoveAll(dir)
+	cfg, err := New([]string{filepath.Join(dir, "docker-compose.yml")}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.CanonicalComposeFile.Version.Equal(v3_9) {
+		t.Fatal(cfg.CanonicalComposeFile.Version)
+	}
+}
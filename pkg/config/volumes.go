@@ -0,0 +1,247 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/uber-go/mapdecode"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	VolumeTypeBind   = "bind"
+	VolumeTypeTmpfs  = "tmpfs"
+	VolumeTypeVolume = "volume"
+)
+
+// PersistentVolumeClaimConfig is the configuration of a named volume in x-kube-compose. Named volumes without such configuration are
+// backed by an emptyDir.
+type PersistentVolumeClaimConfig struct {
+	AccessModes  []string `mapdecode:"access_modes"`
+	Size         string   `mapdecode:"size"`
+	StorageClass *string  `mapdecode:"storage_class"`
+}
+
+// Volume is a named volume declared in the top-level volumes section of a docker compose file.
+type Volume struct {
+	Name                  string
+	PersistentVolumeClaim *PersistentVolumeClaimConfig
+}
+
+// ServiceVolume is the parsed/canonical form of an item of the volumes field of a docker compose service.
+type ServiceVolume struct {
+	ReadOnly bool
+	Source   string // the name of a named volume, an absolute path of a bind mount, or the empty string for anonymous volumes.
+	Target   string // the absolute path in the container.
+	Type     string // one of VolumeTypeBind, VolumeTypeTmpfs and VolumeTypeVolume.
+}
+
+// volumeYAML is a value of the top-level volumes section.
+// https://docs.docker.com/compose/compose-file/compose-file-v2/#volume-configuration-reference
+type volumeYAML struct {
+	Driver     string      `mapdecode:"driver"`
+	DriverOpts interface{} `mapdecode:"driver_opts"`
+	External   interface{} `mapdecode:"external"`
+}
+
+// serviceVolumeYAML is an item of the volumes field of a service, in the short syntax or the long syntax (version 2.3+ and 3.2+).
+type serviceVolumeYAML struct {
+	Value ServiceVolume
+}
+
+func (v *serviceVolumeYAML) Decode(into mapdecode.Into) error {
+	var str string
+	err := into(&str)
+	if err == nil {
+		v.Value, err = parseServiceVolumeShortSyntax(str)
+		return err
+	}
+	var longSyntax struct {
+		ReadOnly bool   `mapdecode:"read_only"`
+		Source   string `mapdecode:"source"`
+		Target   string `mapdecode:"target"`
+		Type     string `mapdecode:"type"`
+	}
+	err = into(&longSyntax)
+	if err != nil {
+		return err
+	}
+	v.Value = ServiceVolume{
+		ReadOnly: longSyntax.ReadOnly,
+		Source:   longSyntax.Source,
+		Target:   longSyntax.Target,
+		Type:     longSyntax.Type,
+	}
+	switch v.Value.Type {
+	case VolumeTypeBind, VolumeTypeVolume:
+	case VolumeTypeTmpfs:
+		if len(v.Value.Source) > 0 {
+			return fmt.Errorf("volume of type %s must not have a source", VolumeTypeTmpfs)
+		}
+	default:
+		return fmt.Errorf("volume has unsupported type %#v", v.Value.Type)
+	}
+	if len(v.Value.Target) == 0 {
+		return fmt.Errorf("volume must have a target")
+	}
+	return nil
+}
+
+// isVolumePath returns true if and only if the source of a volume in the short syntax is a path (i.e. the volume is a bind mount).
+func isVolumePath(source string) bool {
+	return strings.HasPrefix(source, ".") || strings.HasPrefix(source, "/") || strings.HasPrefix(source, "~")
+}
+
+// parseServiceVolumeShortSyntax parses [SOURCE:]TARGET[:MODE], where MODE is a comma separated list of options.
+// https://docs.docker.com/compose/compose-file/compose-file-v2/#short-syntax
+func parseServiceVolumeShortSyntax(str string) (ServiceVolume, error) {
+	parts := strings.Split(str, ":")
+	volume := ServiceVolume{
+		Type: VolumeTypeVolume,
+	}
+	switch len(parts) {
+	case 1:
+		volume.Target = parts[0]
+	case 2, 3:
+		volume.Source = parts[0]
+		volume.Target = parts[1]
+		if len(parts) == 3 {
+			for _, option := range strings.Split(parts[2], ",") {
+				if option == "ro" {
+					volume.ReadOnly = true
+				}
+			}
+		}
+		if isVolumePath(volume.Source) {
+			volume.Type = VolumeTypeBind
+		}
+	default:
+		return volume, fmt.Errorf("invalid volume specification %#v", str)
+	}
+	if len(volume.Target) == 0 {
+		return volume, fmt.Errorf("invalid volume specification %#v", str)
+	}
+	return volume, nil
+}
+
+// resolveVolumePaths makes the sources of bind mounts absolute.
+func resolveVolumePaths(dir string, volumes []interface{}) error {
+	for i, volumeRaw := range volumes {
+		switch volume := volumeRaw.(type) {
+		case string:
+			j := strings.IndexByte(volume, ':')
+			if j < 0 || !isVolumePath(volume[:j]) {
+				continue
+			}
+			source, err := resolveVolumePath(dir, volume[:j])
+			if err != nil {
+				return err
+			}
+			volumes[i] = source + volume[j:]
+		case map[interface{}]interface{}:
+			if volume["type"] != VolumeTypeBind {
+				continue
+			}
+			if source, ok := volume["source"].(string); ok {
+				source, err := resolveVolumePath(dir, source)
+				if err != nil {
+					return err
+				}
+				volume["source"] = source
+			}
+		}
+	}
+	return nil
+}
+
+func resolveVolumePath(dir, source string) (string, error) {
+	if source == "~" || strings.HasPrefix(source, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, source[1:]), nil
+	}
+	return resolvePath(dir, source), nil
+}
+
+func parseVolumes(volumesYAML map[string]*volumeYAML, pvcConfigs map[string]*PersistentVolumeClaimConfig) (map[string]*Volume, error) {
+	volumes := make(map[string]*Volume, len(volumesYAML))
+	for name, volumeYAML := range volumesYAML {
+		if volumeYAML != nil {
+			if volumeYAML.External != nil && volumeYAML.External != false {
+				return nil, fmt.Errorf("volume %s is external, but external volumes are not supported", name)
+			}
+			if len(volumeYAML.Driver) > 0 && volumeYAML.Driver != "local" {
+				return nil, fmt.Errorf("volume %s has driver %s, but only the local driver is supported", name, volumeYAML.Driver)
+			}
+			if volumeYAML.DriverOpts != nil {
+				return nil, fmt.Errorf("volume %s has driver_opts, but driver options are not supported", name)
+			}
+		}
+		volumes[name] = &Volume{
+			Name: name,
+		}
+	}
+	for name, pvcConfig := range pvcConfigs {
+		volume := volumes[name]
+		if volume == nil {
+			return nil, fmt.Errorf("x-kube-compose.volumes refers to a non-existing volume: %s", name)
+		}
+		if pvcConfig == nil {
+			continue
+		}
+		if _, err := resource.ParseQuantity(pvcConfig.Size); err != nil {
+			return nil, fmt.Errorf("x-kube-compose.volumes.%s has an invalid size %#v: %v", name, pvcConfig.Size, err)
+		}
+		volume.PersistentVolumeClaim = pvcConfig
+	}
+	return volumes, nil
+}
+
+func parseServiceVolumes(serviceVolumesYAML []serviceVolumeYAML, volumes map[string]*Volume) ([]ServiceVolume, error) {
+	serviceVolumes := make([]ServiceVolume, len(serviceVolumesYAML))
+	for i, serviceVolumeYAML := range serviceVolumesYAML {
+		serviceVolume := serviceVolumeYAML.Value
+		if serviceVolume.Type == VolumeTypeVolume && len(serviceVolume.Source) > 0 {
+			if _, ok := volumes[serviceVolume.Source]; !ok {
+				return nil, fmt.Errorf("refers to a non-existing volume: %s", serviceVolume.Source)
+			}
+		}
+		if serviceVolume.Type == VolumeTypeBind && !serviceVolume.ReadOnly {
+			return nil, fmt.Errorf("has a bind mount %s that is not read-only, but only read-only bind mounts are supported", serviceVolume.Source)
+		}
+		serviceVolumes[i] = serviceVolume
+	}
+	return serviceVolumes, nil
+}
+
+// ensureVolumesNotShared rejects named volumes that are backed by an emptyDir and used by more than one service. An emptyDir belongs to a
+// single pod, so such services would silently not share any data.
+func ensureVolumesNotShared(services map[string]*Service, volumes map[string]*Volume) error {
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	users := map[string]string{}
+	for _, name := range names {
+		for _, serviceVolume := range services[name].Volumes {
+			if serviceVolume.Type != VolumeTypeVolume || len(serviceVolume.Source) == 0 {
+				continue
+			}
+			if volumes[serviceVolume.Source].PersistentVolumeClaim != nil {
+				continue
+			}
+			if user, ok := users[serviceVolume.Source]; ok && user != name {
+				return fmt.Errorf("volume %s is used by services %s and %s, but named volumes can only be shared by services if they "+
+					"are backed by a PersistentVolumeClaim (see x-kube-compose.volumes)", serviceVolume.Source, user, name)
+			}
+			users[serviceVolume.Source] = name
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package config
+
+import "testing"
+
+func TestParseServiceVolumeShortSyntaxAnonymous(t *testing.T) {
+	volume, err := parseServiceVolumeShortSyntax("/var/lib/mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if volume != (ServiceVolume{Target: "/var/lib/mysql", Type: VolumeTypeVolume}) {
+		t.Fatalf("%#v", volume)
+	}
+}
+
+func TestParseServiceVolumeShortSyntaxNamed(t *testing.T) {
+	volume, err := parseServiceVolumeShortSyntax("datavolume:/var/lib/mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if volume != (ServiceVolume{Source: "datavolume", Target: "/var/lib/mysql", Type: VolumeTypeVolume}) {
+		t.Fatalf("%#v", volume)
+	}
+}
+
+func TestParseServiceVolumeShortSyntaxBindReadOnly(t *testing.T) {
+	volume, err := parseServiceVolumeShortSyntax("./cache:/tmp/cache:ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if volume != (ServiceVolume{ReadOnly: true, Source: "./cache", Target: "/tmp/cache", Type: VolumeTypeBind}) {
+		t.Fatalf("%#v", volume)
+	}
+}
+
+func TestParseServiceVolumeShortSyntaxInvalid(t *testing.T) {
+	_, err := parseServiceVolumeShortSyntax("a:b:c:d")
+	if err == nil {
+		t.Fail()
+	}
+}
+
+func TestResolveVolumePaths(t *testing.T) {
+	volumes := []interface{}{
+		"./cache:/tmp/cache:ro",
+		"datavolume:/var/lib/mysql",
+		map[interface{}]interface{}{
+			"type":   "bind",
+			"source": "config",
+			"target": "/etc/config",
+		},
+	}
+	err := resolveVolumePaths("/home/user/project", volumes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if volumes[0] != "/home/user/project/cache:/tmp/cache:ro" || volumes[1] != "datavolume:/var/lib/mysql" {
+		t.Fatalf("%#v", volumes)
+	}
+	if volumes[2].(map[interface{}]interface{})["source"] != "/home/user/project/config" {
+		t.Fatalf("%#v", volumes[2])
+	}
+}
+
+func TestEnsureVolumesNotShared(t *testing.T) {
+	newServices := func(source string) map[string]*Service {
+		return map[string]*Service{
+			"a": {Volumes: []ServiceVolume{{Source: source, Target: "/data", Type: VolumeTypeVolume}}},
+			"b": {Volumes: []ServiceVolume{{Source: source, Target: "/data", Type: VolumeTypeVolume}}},
+		}
+	}
+	volumes := map[string]*Volume{
+		"emptydir": {Name: "emptydir"},
+		"pvc":      {Name: "pvc", PersistentVolumeClaim: &PersistentVolumeClaimConfig{Size: "1Gi"}},
+	}
+	if err := ensureVolumesNotShared(newServices("emptydir"), volumes); err == nil {
+		t.Fail()
+	}
+	if err := ensureVolumesNotShared(newServices("pvc"), volumes); err != nil {
+		t.Fatal(err)
+	}
+	if err := ensureVolumesNotShared(newServices(""), volumes); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,68 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	dockerTypes "github.com/docker/docker/api/types"
+	dockerClient "github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/moby/patternmatcher/ignorefile"
+)
+
+// ArchiveBuildContext creates a tar archive of the directory dir to be sent to the docker daemon as a build context, excluding files
+// that match the patterns in dir/.dockerignore. Like the docker CLI, the Dockerfile and .dockerignore are never excluded.
+func ArchiveBuildContext(dir, dockerfile string) (io.ReadCloser, error) {
+	var excludes []string
+	file, err := os.Open(filepath.Join(dir, ".dockerignore"))
+	if err == nil {
+		excludes, err = ignorefile.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error while reading .dockerignore: %v", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(excludes) > 0 {
+		excludes = append(excludes, "!"+filepath.ToSlash(filepath.Clean(dockerfile)), "!.dockerignore")
+	}
+	return archive.TarWithOptions(dir, &archive.TarOptions{
+		ExcludePatterns: excludes,
+	})
+}
+
+// BuildImage builds an image from the build context buildContext (a tar archive). onStep is called with each line of the build output
+// that starts a build step (e.g. "Step 1/5 : FROM ubuntu"). The image should be tagged via options so that it can be inspected
+// afterwards.
+func BuildImage(ctx context.Context, dockerClient *dockerClient.Client, buildContext io.Reader, options dockerTypes.ImageBuildOptions, onStep func(string)) error {
+	response, err := dockerClient.ImageBuild(ctx, buildContext, options)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	decoder := json.NewDecoder(response.Body)
+	for {
+		var msg jsonmessage.JSONMessage
+		err := decoder.Decode(&msg)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		if msg.Error != nil && len(msg.Error.Message) > 0 {
+			return fmt.Errorf("error while building image: %s", msg.Error.Message)
+		}
+		if strings.HasPrefix(msg.Stream, "Step ") {
+			onStep(strings.TrimSpace(msg.Stream))
+		}
+	}
+	return nil
+}
@@ -1,24 +1,37 @@
 package down
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/jbrekelmans/kube-compose/pkg/config"
+	k8sUtil "github.com/jbrekelmans/kube-compose/pkg/k8s"
+	k8sError "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	clientAppsV1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+	clientBatchV1 "k8s.io/client-go/kubernetes/typed/batch/v1"
 	clientV1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	networkingV1 "k8s.io/client-go/kubernetes/typed/networking/v1"
 )
 
-type deleter func(name string, options *metav1.DeleteOptions) error
+type deleter func(ctx context.Context, name string, options metav1.DeleteOptions) error
 
-type lister func(listOptions metav1.ListOptions) ([]*v1.ObjectMeta, error)
+type lister func(ctx context.Context, listOptions metav1.ListOptions) ([]*v1.ObjectMeta, error)
 
 type downRunner struct {
-	cfg              *config.Config
-	k8sClientset     *kubernetes.Clientset
-	k8sServiceClient clientV1.ServiceInterface
-	k8sPodClient     clientV1.PodInterface
+	cfg                            *config.Config
+	ctx                            context.Context
+	k8sClientset                   kubernetes.Interface
+	k8sConfigMapClient             clientV1.ConfigMapInterface
+	k8sDeploymentClient            clientAppsV1.DeploymentInterface
+	k8sJobClient                   clientBatchV1.JobInterface
+	k8sNetworkPolicyClient         networkingV1.NetworkPolicyInterface
+	k8sPersistentVolumeClaimClient clientV1.PersistentVolumeClaimInterface
+	k8sServiceClient               clientV1.ServiceInterface
+	k8sPodClient                   clientV1.PodInterface
+	k8sStatefulSetClient           clientAppsV1.StatefulSetInterface
 }
 
 func (d *downRunner) initKubernetesClientset() error {
@@ -27,24 +40,56 @@ func (d *downRunner) initKubernetesClientset() error {
 		return err
 	}
 	d.k8sClientset = k8sClientset
+	d.initClients()
+	return nil
+}
+
+func (d *downRunner) initClients() {
+	d.k8sConfigMapClient = d.k8sClientset.CoreV1().ConfigMaps(d.cfg.Namespace)
+	d.k8sDeploymentClient = d.k8sClientset.AppsV1().Deployments(d.cfg.Namespace)
+	d.k8sJobClient = d.k8sClientset.BatchV1().Jobs(d.cfg.Namespace)
+	d.k8sNetworkPolicyClient = d.k8sClientset.NetworkingV1().NetworkPolicies(d.cfg.Namespace)
+	d.k8sPersistentVolumeClaimClient = d.k8sClientset.CoreV1().PersistentVolumeClaims(d.cfg.Namespace)
 	d.k8sServiceClient = d.k8sClientset.CoreV1().Services(d.cfg.Namespace)
 	d.k8sPodClient = d.k8sClientset.CoreV1().Pods(d.cfg.Namespace)
-	return nil
+	d.k8sStatefulSetClient = d.k8sClientset.AppsV1().StatefulSets(d.cfg.Namespace)
 }
 
+// isEnvironmentResource returns true if the resource with object metadata objectMeta was created by kube-compose, see
+// k8sUtil.IsEnvironmentResource. Older versions of kube-compose did not stamp resources, so resources with the app label and the service
+// annotation (see k8sUtil.AnnotationName) are also deleted.
+func isEnvironmentResource(objectMeta *v1.ObjectMeta, environmentID string) bool {
+	if k8sUtil.IsEnvironmentResource(objectMeta, environmentID) {
+		return true
+	}
+	_, hasAppLabel := objectMeta.Labels["app"]
+	_, hasAnnotationName := objectMeta.Annotations[k8sUtil.AnnotationName]
+	return hasAppLabel && hasAnnotationName
+}
+
+// deleteCommon deletes the resources of one kind of the environment. The per-kind deleters below (deleteServices up to and including
+// deleteStatefulSets) are the legacy path of down: they only run if the environment has no anchor (see deleteAnchor), because the
+// garbage collector of Kubernetes deletes all resources owned by the anchor.
 func (d *downRunner) deleteCommon(errorChannel chan<- error, kind string, lister lister, deleter deleter) {
 	defer close(errorChannel)
 	listOptions := metav1.ListOptions{
 		LabelSelector: d.cfg.EnvironmentLabel + "=" + d.cfg.EnvironmentID,
 	}
-	list, err := lister(listOptions)
+	list, err := lister(d.ctx, listOptions)
 	if err != nil {
 		errorChannel <- err
 		return
 	}
-	deleteOptions := &metav1.DeleteOptions{}
+	deleteOptions := metav1.DeleteOptions{}
 	for _, item := range list {
-		err := deleter(item.Name, deleteOptions)
+		if err := d.ctx.Err(); err != nil {
+			errorChannel <- err
+			return
+		}
+		if !isEnvironmentResource(item, d.cfg.EnvironmentID) {
+			continue
+		}
+		err := deleter(d.ctx, item.Name, deleteOptions)
 		if err != nil {
 			errorChannel <- err
 			return
@@ -54,8 +99,8 @@ func (d *downRunner) deleteCommon(errorChannel chan<- error, kind string, lister
 }
 
 func (d *downRunner) deleteServices(errorChannel chan<- error) {
-	lister := func(listOptions metav1.ListOptions) ([]*v1.ObjectMeta, error) {
-		serviceList, err := d.k8sServiceClient.List(listOptions)
+	lister := func(ctx context.Context, listOptions metav1.ListOptions) ([]*v1.ObjectMeta, error) {
+		serviceList, err := d.k8sServiceClient.List(ctx, listOptions)
 		if err != nil {
 			return nil, err
 		}
@@ -69,8 +114,8 @@ func (d *downRunner) deleteServices(errorChannel chan<- error) {
 }
 
 func (d *downRunner) deletePods(errorChannel chan<- error) {
-	lister := func(listOptions metav1.ListOptions) ([]*v1.ObjectMeta, error) {
-		podList, err := d.k8sPodClient.List(listOptions)
+	lister := func(ctx context.Context, listOptions metav1.ListOptions) ([]*v1.ObjectMeta, error) {
+		podList, err := d.k8sPodClient.List(ctx, listOptions)
 		if err != nil {
 			return nil, err
 		}
@@ -83,17 +128,149 @@ func (d *downRunner) deletePods(errorChannel chan<- error) {
 	d.deleteCommon(errorChannel, "Pod", lister, d.k8sPodClient.Delete)
 }
 
+func (d *downRunner) deleteConfigMaps(errorChannel chan<- error) {
+	lister := func(ctx context.Context, listOptions metav1.ListOptions) ([]*v1.ObjectMeta, error) {
+		configMapList, err := d.k8sConfigMapClient.List(ctx, listOptions)
+		if err != nil {
+			return nil, err
+		}
+		list := make([]*v1.ObjectMeta, len(configMapList.Items))
+		for i := 0; i < len(configMapList.Items); i++ {
+			list[i] = &configMapList.Items[i].ObjectMeta
+		}
+		return list, nil
+	}
+	d.deleteCommon(errorChannel, "ConfigMap", lister, d.k8sConfigMapClient.Delete)
+}
+
+func (d *downRunner) deletePersistentVolumeClaims(errorChannel chan<- error) {
+	lister := func(ctx context.Context, listOptions metav1.ListOptions) ([]*v1.ObjectMeta, error) {
+		pvcList, err := d.k8sPersistentVolumeClaimClient.List(ctx, listOptions)
+		if err != nil {
+			return nil, err
+		}
+		list := make([]*v1.ObjectMeta, len(pvcList.Items))
+		for i := 0; i < len(pvcList.Items); i++ {
+			list[i] = &pvcList.Items[i].ObjectMeta
+		}
+		return list, nil
+	}
+	d.deleteCommon(errorChannel, "PersistentVolumeClaim", lister, d.k8sPersistentVolumeClaimClient.Delete)
+}
+
+func (d *downRunner) deleteNetworkPolicies(errorChannel chan<- error) {
+	lister := func(ctx context.Context, listOptions metav1.ListOptions) ([]*v1.ObjectMeta, error) {
+		networkPolicyList, err := d.k8sNetworkPolicyClient.List(ctx, listOptions)
+		if err != nil {
+			return nil, err
+		}
+		list := make([]*v1.ObjectMeta, len(networkPolicyList.Items))
+		for i := 0; i < len(networkPolicyList.Items); i++ {
+			list[i] = &networkPolicyList.Items[i].ObjectMeta
+		}
+		return list, nil
+	}
+	d.deleteCommon(errorChannel, "NetworkPolicy", lister, d.k8sNetworkPolicyClient.Delete)
+}
+
+// deleteWithDependents returns a deleter that also deletes the pods of workloads, because Jobs orphan their pods by default.
+func deleteWithDependents(deleter deleter) deleter {
+	return func(ctx context.Context, name string, options metav1.DeleteOptions) error {
+		propagationPolicy := metav1.DeletePropagationBackground
+		options.PropagationPolicy = &propagationPolicy
+		return deleter(ctx, name, options)
+	}
+}
+
+func (d *downRunner) deleteJobs(errorChannel chan<- error) {
+	lister := func(ctx context.Context, listOptions metav1.ListOptions) ([]*v1.ObjectMeta, error) {
+		jobList, err := d.k8sJobClient.List(ctx, listOptions)
+		if err != nil {
+			return nil, err
+		}
+		list := make([]*v1.ObjectMeta, len(jobList.Items))
+		for i := 0; i < len(jobList.Items); i++ {
+			list[i] = &jobList.Items[i].ObjectMeta
+		}
+		return list, nil
+	}
+	d.deleteCommon(errorChannel, "Job", lister, deleteWithDependents(d.k8sJobClient.Delete))
+}
+
+func (d *downRunner) deleteDeployments(errorChannel chan<- error) {
+	lister := func(ctx context.Context, listOptions metav1.ListOptions) ([]*v1.ObjectMeta, error) {
+		deploymentList, err := d.k8sDeploymentClient.List(ctx, listOptions)
+		if err != nil {
+			return nil, err
+		}
+		list := make([]*v1.ObjectMeta, len(deploymentList.Items))
+		for i := 0; i < len(deploymentList.Items); i++ {
+			list[i] = &deploymentList.Items[i].ObjectMeta
+		}
+		return list, nil
+	}
+	d.deleteCommon(errorChannel, "Deployment", lister, deleteWithDependents(d.k8sDeploymentClient.Delete))
+}
+
+func (d *downRunner) deleteStatefulSets(errorChannel chan<- error) {
+	lister := func(ctx context.Context, listOptions metav1.ListOptions) ([]*v1.ObjectMeta, error) {
+		statefulSetList, err := d.k8sStatefulSetClient.List(ctx, listOptions)
+		if err != nil {
+			return nil, err
+		}
+		list := make([]*v1.ObjectMeta, len(statefulSetList.Items))
+		for i := 0; i < len(statefulSetList.Items); i++ {
+			list[i] = &statefulSetList.Items[i].ObjectMeta
+		}
+		return list, nil
+	}
+	d.deleteCommon(errorChannel, "StatefulSet", lister, deleteWithDependents(d.k8sStatefulSetClient.Delete))
+}
+
+// deleteAnchor deletes the anchor ConfigMap of the environment, whose dependents are deleted by the garbage collector of Kubernetes.
+// Returns false if the anchor does not exist, for example because the environment was created by an older version of kube-compose.
+func (d *downRunner) deleteAnchor() (bool, error) {
+	name := k8sUtil.AnchorName(d.cfg.EnvironmentID)
+	propagationPolicy := metav1.DeletePropagationForeground
+	err := d.k8sConfigMapClient.Delete(d.ctx, name, metav1.DeleteOptions{
+		PropagationPolicy: &propagationPolicy,
+	})
+	if k8sError.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	fmt.Printf("deleted ConfigMap %s and all resources it owns\n", name)
+	return true, nil
+}
+
 func (d *downRunner) run() error {
 	err := d.initKubernetesClientset()
 	if err != nil {
 		return err
 	}
-	errorChannels := make([]chan error, 2)
+	return d.deleteEnvironment()
+}
+
+func (d *downRunner) deleteEnvironment() error {
+	deleted, err := d.deleteAnchor()
+	if err != nil || deleted {
+		return err
+	}
+	// Legacy path: the environment has no anchor (e.g. it was created by an older version of kube-compose), so its resources are deleted
+	// one by one, per kind.
+	errorChannels := make([]chan error, 8)
 	for i := 0; i < len(errorChannels); i++ {
 		errorChannels[i] = make(chan error, 1)
 	}
 	go d.deleteServices(errorChannels[0])
 	go d.deletePods(errorChannels[1])
+	go d.deleteConfigMaps(errorChannels[2])
+	go d.deletePersistentVolumeClaims(errorChannels[3])
+	go d.deleteNetworkPolicies(errorChannels[4])
+	go d.deleteJobs(errorChannels[5])
+	go d.deleteDeployments(errorChannels[6])
+	go d.deleteStatefulSets(errorChannels[7])
 	var firstError error
 	for i := 0; i < len(errorChannels); i++ {
 		err, more := <-errorChannels[i]
@@ -109,10 +286,12 @@ func (d *downRunner) run() error {
 	return firstError
 }
 
-// Run runs a docker-compose down command...
-func Run(cfg *config.Config) error {
+// Run runs a docker-compose down command, which deletes the anchor of the environment and thereby all resources it owns, or (if there
+// is no anchor) deletes the resources of the environment per kind. Deleting resources is aborted when ctx is done.
+func Run(ctx context.Context, cfg *config.Config) error {
 	d := &downRunner{
 		cfg: cfg,
+		ctx: ctx,
 	}
 	return d.run()
 }
@@ -0,0 +1,102 @@
+package down
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jbrekelmans/kube-compose/pkg/config"
+	k8sUtil "github.com/jbrekelmans/kube-compose/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+	k8sError "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testEnvironmentID = "test123"
+
+func newTestDownRunner(objects ...runtime.Object) *downRunner {
+	d := &downRunner{
+		cfg: &config.Config{
+			EnvironmentID:    testEnvironmentID,
+			EnvironmentLabel: config.DefaultEnvironmentLabel,
+			Namespace:        "default",
+		},
+		ctx:          context.Background(),
+		k8sClientset: fake.NewSimpleClientset(objects...),
+	}
+	d.initClients()
+	return d
+}
+
+// newTestObjectMeta returns the object metadata of a resource named name in the environment with id environmentID. If service is not
+// empty the resource has the app label and service annotation of kube-compose. If stamped is true the resource has a creation time.
+func newTestObjectMeta(name, environmentID, service string, stamped bool) metav1.ObjectMeta {
+	objectMeta := metav1.ObjectMeta{
+		Name:        name,
+		Namespace:   "default",
+		Labels:      map[string]string{config.DefaultEnvironmentLabel: environmentID},
+		Annotations: map[string]string{},
+	}
+	if len(service) > 0 {
+		objectMeta.Labels["app"] = service
+		objectMeta.Annotations[k8sUtil.AnnotationName] = service
+	}
+	if stamped {
+		objectMeta.Annotations[k8sUtil.AnnotationCreated] = "2026-10-16T12:00:00Z"
+	}
+	return objectMeta
+}
+
+func TestDeleteEnvironmentWithoutAnchor(t *testing.T) {
+	d := newTestDownRunner(
+		// Resources of an environment created by an older version of kube-compose, which did not stamp resources.
+		&v1.Pod{ObjectMeta: newTestObjectMeta("web-test123", testEnvironmentID, "web", false)},
+		&v1.Service{ObjectMeta: newTestObjectMeta("web-test123", testEnvironmentID, "web", false)},
+		&v1.PersistentVolumeClaim{ObjectMeta: newTestObjectMeta("data-test123", testEnvironmentID, "", true)},
+		// Resources that kube-compose did not create, or of another environment.
+		&v1.ConfigMap{ObjectMeta: newTestObjectMeta("other-test123", testEnvironmentID, "", false)},
+		&v1.Pod{ObjectMeta: newTestObjectMeta("web-other", "other", "web", false)},
+	)
+	err := d.deleteEnvironment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err = d.k8sPodClient.Get(ctx, "web-test123", metav1.GetOptions{}); !k8sError.IsNotFound(err) {
+		t.Errorf("expected the unstamped pod to be deleted, but got error %v", err)
+	}
+	if _, err = d.k8sServiceClient.Get(ctx, "web-test123", metav1.GetOptions{}); !k8sError.IsNotFound(err) {
+		t.Errorf("expected the unstamped service to be deleted, but got error %v", err)
+	}
+	if _, err = d.k8sPersistentVolumeClaimClient.Get(ctx, "data-test123", metav1.GetOptions{}); !k8sError.IsNotFound(err) {
+		t.Errorf("expected the stamped persistent volume claim to be deleted, but got error %v", err)
+	}
+	if _, err = d.k8sConfigMapClient.Get(ctx, "other-test123", metav1.GetOptions{}); err != nil {
+		t.Errorf("expected the config map of another tool to be kept, but got error %v", err)
+	}
+	if _, err = d.k8sPodClient.Get(ctx, "web-other", metav1.GetOptions{}); err != nil {
+		t.Errorf("expected the pod of another environment to be kept, but got error %v", err)
+	}
+}
+
+func TestDeleteEnvironmentWithAnchor(t *testing.T) {
+	d := newTestDownRunner(
+		&v1.ConfigMap{ObjectMeta: newTestObjectMeta(k8sUtil.AnchorName(testEnvironmentID), testEnvironmentID, "", true)},
+		&v1.Pod{ObjectMeta: newTestObjectMeta("web-test123", testEnvironmentID, "web", true)},
+	)
+	err := d.deleteEnvironment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientset := d.k8sClientset.(*fake.Clientset)
+	for _, action := range clientset.Actions() {
+		if action.GetVerb() != "delete" || action.GetResource().Resource != "configmaps" {
+			t.Errorf("expected only the anchor to be deleted, but got action %s %s", action.GetVerb(), action.GetResource().Resource)
+		}
+	}
+	_, err = d.k8sConfigMapClient.Get(context.Background(), k8sUtil.AnchorName(testEnvironmentID), metav1.GetOptions{})
+	if !k8sError.IsNotFound(err) {
+		t.Errorf("expected the anchor to be deleted, but got error %v", err)
+	}
+}
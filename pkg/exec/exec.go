@@ -0,0 +1,150 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jbrekelmans/kube-compose/pkg/config"
+	k8sUtil "github.com/jbrekelmans/kube-compose/pkg/k8s"
+	"github.com/moby/term"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+	k8sExec "k8s.io/client-go/util/exec"
+)
+
+// Options are the options of the exec command.
+type Options struct {
+	Command []string
+	Index   int // the index of the pod of the service, see k8sUtil.GroupPodsByService.
+	Service string
+	Stdin   bool
+	TTY     bool
+}
+
+type execRunner struct {
+	cfg          *config.Config
+	k8sClientset kubernetes.Interface
+}
+
+// terminalSizeQueue reports the size of the terminal once, so that the remote TTY has the size of the local terminal.
+type terminalSizeQueue struct {
+	size *remotecommand.TerminalSize
+}
+
+func (q *terminalSizeQueue) Next() *remotecommand.TerminalSize {
+	size := q.size
+	q.size = nil
+	return size
+}
+
+func (e *execRunner) initKubernetesClientset() error {
+	k8sClientset, err := kubernetes.NewForConfig(e.cfg.KubeConfig)
+	if err != nil {
+		return err
+	}
+	e.k8sClientset = k8sClientset
+	return nil
+}
+
+// findPod returns the pod with index index of the docker compose service named name, which must be running.
+func (e *execRunner) findPod(name string, index int) (*v1.Pod, error) {
+	if _, ok := e.cfg.CanonicalComposeFile.Services[name]; !ok {
+		return nil, fmt.Errorf("no service named %#v exists", name)
+	}
+	if index < 1 {
+		return nil, fmt.Errorf("index must be positive, but got %d", index)
+	}
+	listOptions := metav1.ListOptions{
+		LabelSelector: e.cfg.EnvironmentLabel + "=" + e.cfg.EnvironmentID,
+	}
+	podList, err := e.k8sClientset.CoreV1().Pods(e.cfg.Namespace).List(context.Background(), listOptions)
+	if err != nil {
+		return nil, err
+	}
+	pods := k8sUtil.GroupPodsByService(podList.Items)[name]
+	if index > len(pods) {
+		return nil, fmt.Errorf("app %s: no pod with index %d found, the service has %d pods", name, index, len(pods))
+	}
+	pod := pods[index-1]
+	if pod.Status.Phase != v1.PodRunning {
+		return nil, fmt.Errorf("app %s: pod %s is not running (phase %s)", name, pod.ObjectMeta.Name, pod.Status.Phase)
+	}
+	return pod, nil
+}
+
+func (e *execRunner) run(opts *Options) (int, error) {
+	if len(opts.Command) == 0 {
+		return 0, fmt.Errorf("a command is required")
+	}
+	err := e.initKubernetesClientset()
+	if err != nil {
+		return 0, err
+	}
+	pod, err := e.findPod(opts.Service, opts.Index)
+	if err != nil {
+		return 0, err
+	}
+	streamOptions := remotecommand.StreamOptions{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		Tty:    opts.TTY,
+	}
+	if opts.Stdin {
+		streamOptions.Stdin = os.Stdin
+	}
+	if opts.TTY {
+		// With a TTY stdout and stderr are multiplexed by the remote terminal.
+		streamOptions.Stderr = nil
+		fd, isTerminal := term.GetFdInfo(os.Stdin)
+		if isTerminal {
+			if winsize, err := term.GetWinsize(fd); err == nil {
+				streamOptions.TerminalSizeQueue = &terminalSizeQueue{
+					size: &remotecommand.TerminalSize{
+						Height: winsize.Height,
+						Width:  winsize.Width,
+					},
+				}
+			}
+			if opts.Stdin {
+				state, err := term.SetRawTerminal(fd)
+				if err != nil {
+					return 0, err
+				}
+				defer term.RestoreTerminal(fd, state) //nolint
+			}
+		}
+	}
+	req := e.k8sClientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(pod.ObjectMeta.Name).
+		Namespace(pod.ObjectMeta.Namespace).
+		SubResource("exec").
+		VersionedParams(&v1.PodExecOptions{
+			Command: opts.Command,
+			Stdin:   opts.Stdin,
+			Stdout:  true,
+			Stderr:  !opts.TTY,
+			TTY:     opts.TTY,
+		}, scheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(e.cfg.KubeConfig, "POST", req.URL())
+	if err != nil {
+		return 0, err
+	}
+	err = executor.StreamWithContext(context.Background(), streamOptions)
+	if exitError, ok := err.(k8sExec.ExitError); ok && exitError.Exited() {
+		return exitError.ExitStatus(), nil
+	}
+	return 0, err
+}
+
+// Run runs an operation similar to docker-compose exec against a Kubernetes cluster, and returns the exit code of the command.
+func Run(cfg *config.Config, opts *Options) (int, error) {
+	e := &execRunner{
+		cfg: cfg,
+	}
+	return e.run(opts)
+}
@@ -0,0 +1,84 @@
+package exec
+
+import (
+	"testing"
+
+	"github.com/jbrekelmans/kube-compose/pkg/config"
+	k8sUtil "github.com/jbrekelmans/kube-compose/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+func newTestPod(name, replica string, phase v1.PodPhase) runtime.Object {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Annotations: map[string]string{
+				k8sUtil.AnnotationName:    "web",
+				k8sUtil.AnnotationReplica: replica,
+			},
+			Labels: map[string]string{
+				config.DefaultEnvironmentLabel: "test123",
+			},
+		},
+		Status: v1.PodStatus{
+			Phase: phase,
+		},
+	}
+}
+
+func TestFindPod(t *testing.T) {
+	e := &execRunner{
+		cfg: &config.Config{
+			CanonicalComposeFile: config.CanonicalComposeFile{
+				Services: map[string]*config.Service{
+					"web": {},
+				},
+			},
+			EnvironmentID:    "test123",
+			EnvironmentLabel: config.DefaultEnvironmentLabel,
+			Namespace:        "default",
+		},
+		k8sClientset: fake.NewSimpleClientset(
+			newTestPod("web-1-test123", "1", v1.PodPending),
+			newTestPod("web-2-test123", "2", v1.PodRunning),
+		),
+	}
+	pod, err := e.findPod("web", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pod.ObjectMeta.Name != "web-2-test123" {
+		t.Errorf("expected pod web-2-test123, but got %s", pod.ObjectMeta.Name)
+	}
+	testCases := []struct {
+		name  string
+		index int
+	}{
+		{"db", 1},  // no such service
+		{"web", 0}, // indexes start at 1
+		{"web", 3}, // the service has 2 pods
+		{"web", 1}, // the pod is not running
+	}
+	for _, testCase := range testCases {
+		_, err := e.findPod(testCase.name, testCase.index)
+		if err == nil {
+			t.Errorf("%s %d: expected an error", testCase.name, testCase.index)
+		}
+	}
+}
+
+func TestTerminalSizeQueue(t *testing.T) {
+	size := &remotecommand.TerminalSize{Height: 24, Width: 80}
+	q := &terminalSizeQueue{size: size}
+	if q.Next() != size {
+		t.Error("expected the size of the terminal")
+	}
+	if q.Next() != nil {
+		t.Error("expected the size of the terminal to be reported once")
+	}
+}
@@ -0,0 +1,317 @@
+package gc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jbrekelmans/kube-compose/pkg/config"
+	"github.com/jbrekelmans/kube-compose/pkg/down"
+	k8sUtil "github.com/jbrekelmans/kube-compose/pkg/k8s"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Options are the options of gc.
+type Options struct {
+	// CIJobLabel is the label of the pods of CI jobs that holds the id of the job. If not empty, environments created by a CI job (see
+	// up's --ci-job-id) are deleted once no pod with that job id exists.
+	CIJobLabel string
+	// CIJobNamespace is the namespace of the pods of CI jobs, or the namespace of gc if empty.
+	CIJobNamespace string
+	DryRun         bool // whether to only print the environments that would be deleted.
+}
+
+// environment summarizes the resources of an environment.
+type environment struct {
+	ciJobIDs     map[string]bool
+	created      time.Time // the latest time at which up created resources of the environment, zero if no resource is stamped.
+	expires      time.Time // the latest expiry of the resources of the environment.
+	id           string
+	neverExpires bool // whether a stamped resource of the environment has no TTL.
+	reason       string
+	resources    int
+}
+
+type gcRunner struct {
+	cfg          *config.Config
+	ctx          context.Context
+	k8sClientset *kubernetes.Clientset
+	now          time.Time
+	opts         *Options
+}
+
+func (g *gcRunner) initKubernetesClientset() error {
+	k8sClientset, err := kubernetes.NewForConfig(g.cfg.KubeConfig)
+	if err != nil {
+		return err
+	}
+	g.k8sClientset = k8sClientset
+	return nil
+}
+
+// listResources returns the object metadata of all resources in the namespace that have the environment label, of the kinds that
+// down deletes. Only resources created by kube-compose are returned, see k8sUtil.IsEnvironmentResource.
+func (g *gcRunner) listResources() ([]metav1.Object, error) {
+	namespace := g.cfg.Namespace
+	listers := []func(listOptions metav1.ListOptions) (runtime.Object, error){
+		func(listOptions metav1.ListOptions) (runtime.Object, error) {
+			return g.k8sClientset.CoreV1().ConfigMaps(namespace).List(g.ctx, listOptions)
+		},
+		func(listOptions metav1.ListOptions) (runtime.Object, error) {
+			return g.k8sClientset.AppsV1().Deployments(namespace).List(g.ctx, listOptions)
+		},
+		func(listOptions metav1.ListOptions) (runtime.Object, error) {
+			return g.k8sClientset.BatchV1().Jobs(namespace).List(g.ctx, listOptions)
+		},
+		func(listOptions metav1.ListOptions) (runtime.Object, error) {
+			return g.k8sClientset.NetworkingV1().NetworkPolicies(namespace).List(g.ctx, listOptions)
+		},
+		func(listOptions metav1.ListOptions) (runtime.Object, error) {
+			return g.k8sClientset.CoreV1().PersistentVolumeClaims(namespace).List(g.ctx, listOptions)
+		},
+		func(listOptions metav1.ListOptions) (runtime.Object, error) {
+			return g.k8sClientset.CoreV1().Pods(namespace).List(g.ctx, listOptions)
+		},
+		func(listOptions metav1.ListOptions) (runtime.Object, error) {
+			return g.k8sClientset.CoreV1().Services(namespace).List(g.ctx, listOptions)
+		},
+		func(listOptions metav1.ListOptions) (runtime.Object, error) {
+			return g.k8sClientset.AppsV1().StatefulSets(namespace).List(g.ctx, listOptions)
+		},
+	}
+	listOptions := metav1.ListOptions{
+		LabelSelector: g.cfg.EnvironmentLabel,
+	}
+	var resources []metav1.Object
+	for _, lister := range listers {
+		list, err := lister(listOptions)
+		if err != nil {
+			return nil, err
+		}
+		err = meta.EachListItem(list, func(item runtime.Object) error {
+			resource, err := meta.Accessor(item)
+			if err != nil {
+				return err
+			}
+			if k8sUtil.IsEnvironmentResource(resource, resource.GetLabels()[g.cfg.EnvironmentLabel]) {
+				resources = append(resources, resource)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resources, nil
+}
+
+// getCIJobNamespace returns the namespace of the pods of CI jobs, see Options.CIJobNamespace.
+func (g *gcRunner) getCIJobNamespace() string {
+	if len(g.opts.CIJobNamespace) > 0 {
+		return g.opts.CIJobNamespace
+	}
+	return g.cfg.Namespace
+}
+
+// listCIJobIDs returns the ids of the CI jobs that still have pods, see Options.CIJobLabel.
+func (g *gcRunner) listCIJobIDs() (map[string]bool, error) {
+	podList, err := g.k8sClientset.CoreV1().Pods(g.getCIJobNamespace()).List(g.ctx, metav1.ListOptions{
+		LabelSelector: g.opts.CIJobLabel,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ciJobIDs := map[string]bool{}
+	for _, pod := range podList.Items {
+		ciJobIDs[pod.ObjectMeta.Labels[g.opts.CIJobLabel]] = true
+	}
+	return ciJobIDs, nil
+}
+
+// updateEnvironment adds resource to the summary of its environment. Resources that up did not stamp (i.e. the anchor of an environment
+// created by an older version of kube-compose) do not affect the expiry of the environment.
+func updateEnvironment(env *environment, resource metav1.Object) error {
+	env.resources++
+	annotations := resource.GetAnnotations()
+	if ciJobID, ok := annotations[k8sUtil.AnnotationCIJob]; ok {
+		env.ciJobIDs[ciJobID] = true
+	}
+	createdValue, ok := annotations[k8sUtil.AnnotationCreated]
+	if !ok {
+		return nil
+	}
+	created, err := time.Parse(time.RFC3339, createdValue)
+	if err != nil {
+		return fmt.Errorf("resource %s has an invalid annotation %s: %v", resource.GetName(), k8sUtil.AnnotationCreated, err)
+	}
+	if created.After(env.created) {
+		env.created = created
+	}
+	ttlValue, ok := annotations[k8sUtil.AnnotationTTL]
+	if !ok {
+		env.neverExpires = true
+		return nil
+	}
+	ttl, err := time.ParseDuration(ttlValue)
+	if err != nil {
+		return fmt.Errorf("resource %s has an invalid annotation %s: %v", resource.GetName(), k8sUtil.AnnotationTTL, err)
+	}
+	if expires := created.Add(ttl); expires.After(env.expires) {
+		env.expires = expires
+	}
+	return nil
+}
+
+// getEnvironments returns the summaries of all environments in the namespace, sorted by id.
+func (g *gcRunner) getEnvironments() ([]*environment, error) {
+	resources, err := g.listResources()
+	if err != nil {
+		return nil, err
+	}
+	environmentMap := map[string]*environment{}
+	for _, resource := range resources {
+		id := resource.GetLabels()[g.cfg.EnvironmentLabel]
+		env := environmentMap[id]
+		if env == nil {
+			env = &environment{
+				ciJobIDs: map[string]bool{},
+				id:       id,
+			}
+			environmentMap[id] = env
+		}
+		err = updateEnvironment(env, resource)
+		if err != nil {
+			return nil, err
+		}
+	}
+	environments := make([]*environment, 0, len(environmentMap))
+	for _, env := range environmentMap {
+		environments = append(environments, env)
+	}
+	sort.Slice(environments, func(i, j int) bool {
+		return environments[i].id < environments[j].id
+	})
+	return environments, nil
+}
+
+// updateReason sets why env should be deleted, or leaves the reason empty if env should be kept. liveCIJobIDs is nil unless
+// Options.CIJobLabel is set. Environments are not deleted because their CI job is gone if liveCIJobIDs is empty, because then the label
+// or namespace of the pods of CI jobs is most likely wrong.
+func (g *gcRunner) updateReason(env *environment, liveCIJobIDs map[string]bool) {
+	if !env.created.IsZero() && !env.neverExpires && g.now.After(env.expires) {
+		env.reason = "expired"
+		return
+	}
+	if len(liveCIJobIDs) == 0 || len(env.ciJobIDs) == 0 {
+		return
+	}
+	for ciJobID := range env.ciJobIDs {
+		if liveCIJobIDs[ciJobID] {
+			return
+		}
+	}
+	env.reason = "ci job gone"
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (g *gcRunner) printTable(environments []*environment) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "ENVIRONMENT\tRESOURCES\tCREATED\tEXPIRES\tCI JOB\tACTION")
+	for _, env := range environments {
+		created := ""
+		expires := ""
+		if !env.created.IsZero() {
+			created = env.created.Format(time.RFC3339)
+			expires = "never"
+			if !env.neverExpires {
+				expires = env.expires.Format(time.RFC3339)
+			}
+		}
+		action := "keep"
+		if len(env.reason) > 0 {
+			action = "delete"
+			if g.opts.DryRun {
+				action = "would delete"
+			}
+			action += " (" + env.reason + ")"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n",
+			env.id,
+			env.resources,
+			created,
+			expires,
+			strings.Join(sortedKeys(env.ciJobIDs), ","),
+			action,
+		)
+	}
+	return w.Flush()
+}
+
+func (g *gcRunner) run() error {
+	err := g.initKubernetesClientset()
+	if err != nil {
+		return err
+	}
+	environments, err := g.getEnvironments()
+	if err != nil {
+		return err
+	}
+	var liveCIJobIDs map[string]bool
+	if len(g.opts.CIJobLabel) > 0 {
+		liveCIJobIDs, err = g.listCIJobIDs()
+		if err != nil {
+			return err
+		}
+		if len(liveCIJobIDs) == 0 {
+			fmt.Printf("no pods with label %s found in namespace %s, so no environment is deleted because its CI job is gone\n",
+				g.opts.CIJobLabel, g.getCIJobNamespace())
+		}
+	}
+	for _, env := range environments {
+		g.updateReason(env, liveCIJobIDs)
+	}
+	err = g.printTable(environments)
+	if err != nil || g.opts.DryRun {
+		return err
+	}
+	for _, env := range environments {
+		if len(env.reason) == 0 {
+			continue
+		}
+		cfg := *g.cfg
+		cfg.EnvironmentID = env.id
+		err = down.Run(g.ctx, &cfg)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("deleted environment %s\n", env.id)
+	}
+	return nil
+}
+
+// Run deletes the environments in the namespace that expired (see up's --ttl), or whose CI job is gone (see Options.CIJobLabel).
+// Deleting environments is aborted when ctx is done.
+func Run(ctx context.Context, cfg *config.Config, opts *Options) error {
+	g := &gcRunner{
+		cfg:  cfg,
+		ctx:  ctx,
+		now:  time.Now(),
+		opts: opts,
+	}
+	return g.run()
+}
@@ -0,0 +1,207 @@
+package gc
+
+import (
+	"testing"
+	"time"
+
+	k8sUtil "github.com/jbrekelmans/kube-compose/pkg/k8s"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestEnvironment() *environment {
+	return &environment{
+		ciJobIDs: map[string]bool{},
+		id:       "env1",
+	}
+}
+
+func TestUpdateEnvironment(t *testing.T) {
+	created := time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name             string
+		annotations      []map[string]string
+		expectError      bool
+		expectedCIJobIDs []string
+		expectedCreated  time.Time
+		expectedExpires  time.Time
+		expectedNever    bool
+	}{
+		{
+			name:        "not stamped",
+			annotations: []map[string]string{nil},
+		},
+		{
+			name: "ttl",
+			annotations: []map[string]string{
+				{
+					k8sUtil.AnnotationCreated: created.Format(time.RFC3339),
+					k8sUtil.AnnotationTTL:     "2h0m0s",
+					k8sUtil.AnnotationCIJob:   "123",
+				},
+			},
+			expectedCIJobIDs: []string{"123"},
+			expectedCreated:  created,
+			expectedExpires:  created.Add(2 * time.Hour),
+		},
+		{
+			name: "latest expiry",
+			annotations: []map[string]string{
+				{
+					k8sUtil.AnnotationCreated: created.Format(time.RFC3339),
+					k8sUtil.AnnotationTTL:     "3h",
+				},
+				{
+					k8sUtil.AnnotationCreated: created.Add(time.Hour).Format(time.RFC3339),
+					k8sUtil.AnnotationTTL:     "1h",
+				},
+			},
+			expectedCreated: created.Add(time.Hour),
+			expectedExpires: created.Add(3 * time.Hour),
+		},
+		{
+			name: "no ttl",
+			annotations: []map[string]string{
+				{
+					k8sUtil.AnnotationCreated: created.Format(time.RFC3339),
+				},
+			},
+			expectedCreated: created,
+			expectedNever:   true,
+		},
+		{
+			name: "invalid created",
+			annotations: []map[string]string{
+				{
+					k8sUtil.AnnotationCreated: "yesterday",
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid ttl",
+			annotations: []map[string]string{
+				{
+					k8sUtil.AnnotationCreated: created.Format(time.RFC3339),
+					k8sUtil.AnnotationTTL:     "2 hours",
+				},
+			},
+			expectError: true,
+		},
+	}
+	for _, testCase := range testCases {
+		env := newTestEnvironment()
+		var err error
+		for _, annotations := range testCase.annotations {
+			err = updateEnvironment(env, &metav1.ObjectMeta{
+				Annotations: annotations,
+				Name:        "resource1",
+			})
+			if err != nil {
+				break
+			}
+		}
+		if testCase.expectError {
+			if err == nil {
+				t.Errorf("%s: expected error", testCase.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", testCase.name, err)
+			continue
+		}
+		if env.resources != len(testCase.annotations) {
+			t.Errorf("%s: expected %d resources, but got %d", testCase.name, len(testCase.annotations), env.resources)
+		}
+		if len(env.ciJobIDs) != len(testCase.expectedCIJobIDs) {
+			t.Errorf("%s: expected CI job ids %v, but got %v", testCase.name, testCase.expectedCIJobIDs, env.ciJobIDs)
+		}
+		for _, ciJobID := range testCase.expectedCIJobIDs {
+			if !env.ciJobIDs[ciJobID] {
+				t.Errorf("%s: expected CI job id %s", testCase.name, ciJobID)
+			}
+		}
+		if !env.created.Equal(testCase.expectedCreated) {
+			t.Errorf("%s: expected created %v, but got %v", testCase.name, testCase.expectedCreated, env.created)
+		}
+		if !env.expires.Equal(testCase.expectedExpires) {
+			t.Errorf("%s: expected expires %v, but got %v", testCase.name, testCase.expectedExpires, env.expires)
+		}
+		if env.neverExpires != testCase.expectedNever {
+			t.Errorf("%s: expected neverExpires %v, but got %v", testCase.name, testCase.expectedNever, env.neverExpires)
+		}
+	}
+}
+
+func TestUpdateReason(t *testing.T) {
+	now := time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+	g := &gcRunner{
+		now: now,
+	}
+	testCases := []struct {
+		name           string
+		created        time.Time
+		expires        time.Time
+		neverExpires   bool
+		ciJobIDs       []string
+		liveCIJobIDs   map[string]bool
+		expectedReason string
+	}{
+		{
+			name:           "expired",
+			created:        now.Add(-2 * time.Hour),
+			expires:        now.Add(-time.Hour),
+			expectedReason: "expired",
+		},
+		{
+			name:    "not expired",
+			created: now.Add(-2 * time.Hour),
+			expires: now.Add(time.Hour),
+		},
+		{
+			name:         "never expires",
+			created:      now.Add(-2 * time.Hour),
+			neverExpires: true,
+		},
+		{
+			name: "not stamped",
+		},
+		{
+			name:     "ci job label not set",
+			ciJobIDs: []string{"123"},
+		},
+		{
+			name:           "ci job gone",
+			ciJobIDs:       []string{"123"},
+			liveCIJobIDs:   map[string]bool{"456": true},
+			expectedReason: "ci job gone",
+		},
+		{
+			name:         "ci job live",
+			ciJobIDs:     []string{"123", "456"},
+			liveCIJobIDs: map[string]bool{"456": true},
+		},
+		{
+			name:         "no ci job pods found",
+			ciJobIDs:     []string{"123"},
+			liveCIJobIDs: map[string]bool{},
+		},
+		{
+			name:         "no ci job",
+			liveCIJobIDs: map[string]bool{"456": true},
+		},
+	}
+	for _, testCase := range testCases {
+		env := newTestEnvironment()
+		env.created = testCase.created
+		env.expires = testCase.expires
+		env.neverExpires = testCase.neverExpires
+		for _, ciJobID := range testCase.ciJobIDs {
+			env.ciJobIDs[ciJobID] = true
+		}
+		g.updateReason(env, testCase.liveCIJobIDs)
+		if env.reason != testCase.expectedReason {
+			t.Errorf("%s: expected reason %#v, but got %#v", testCase.name, testCase.expectedReason, env.reason)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+package k8s
+
+// AnchorDataKey is the key of the data of the anchor ConfigMap of an environment that holds the resolved docker compose file.
+const AnchorDataKey = "docker-compose.yml"
+
+// AnchorName returns the name of the anchor ConfigMap of the environment with id environmentID, which owns all other resources of the
+// environment.
+func AnchorName(environmentID string) string {
+	return "kube-compose-" + environmentID
+}
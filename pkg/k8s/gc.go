@@ -0,0 +1,25 @@
+package k8s
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Annotations that up stamps on the resources of an environment, so that kube-compose gc can find environments to delete.
+const (
+	// AnnotationCIJob holds the id of the CI job that created the resource.
+	AnnotationCIJob = "kube-compose/ci-job"
+	// AnnotationCreated holds the time at which up started creating the environment, in RFC 3339 format.
+	AnnotationCreated = "kube-compose/created"
+	// AnnotationTTL holds the duration after creation after which the environment expires, in the format of time.ParseDuration.
+	AnnotationTTL = "kube-compose/ttl"
+)
+
+// IsEnvironmentResource returns true if the resource with object metadata objectMeta was created by kube-compose for the environment with
+// id environmentID, because it is stamped with AnnotationCreated or it is the anchor of the environment. Resources of other tools that
+// happen to have the environment label are not.
+func IsEnvironmentResource(objectMeta metav1.Object, environmentID string) bool {
+	if _, ok := objectMeta.GetAnnotations()[AnnotationCreated]; ok {
+		return true
+	}
+	return objectMeta.GetName() == AnchorName(environmentID)
+}
@@ -0,0 +1,45 @@
+package k8s
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsEnvironmentResource(t *testing.T) {
+	testCases := []struct {
+		objectMeta *metav1.ObjectMeta
+		expected   bool
+	}{
+		{
+			objectMeta: &metav1.ObjectMeta{
+				Annotations: map[string]string{
+					AnnotationCreated: "2019-01-01T12:00:00Z",
+				},
+				Name: "web-env1",
+			},
+			expected: true,
+		},
+		{
+			objectMeta: &metav1.ObjectMeta{
+				Name: AnchorName("env1"),
+			},
+			expected: true,
+		},
+		{
+			objectMeta: &metav1.ObjectMeta{
+				Name: AnchorName("env2"),
+			},
+		},
+		{
+			objectMeta: &metav1.ObjectMeta{
+				Name: "web-env1",
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		if actual := IsEnvironmentResource(testCase.objectMeta, "env1"); actual != testCase.expected {
+			t.Errorf("%s: expected %v, but got %v", testCase.objectMeta.Name, testCase.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package k8s
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// AnnotationName is the annotation of pods and services that holds the name of the docker compose service they were created for.
+	AnnotationName = "kube-compose/service"
+	// AnnotationReplica is the annotation of pods of services with multiple replicas that holds the number of the replica (starting at 1).
+	AnnotationReplica = "kube-compose/replica"
+)
+
+type PodStatus int
+
+const (
+	// PodStatusCompleted is the status of a pod whose containers all exited with code 0.
+	PodStatusCompleted PodStatus = 3
+	PodStatusReady     PodStatus = 2
+	PodStatusStarted   PodStatus = 1
+	PodStatusOther     PodStatus = 0
+)
+
+func (podStatus *PodStatus) String() string {
+	switch *podStatus {
+	case PodStatusCompleted:
+		return "completed"
+	case PodStatusReady:
+		return "ready"
+	case PodStatusStarted:
+		return "started"
+	}
+	return "other"
+}
+
+// WillRestart returns whether the container that terminated with state t will be restarted by the kubelet, according to the restart
+// policy of pod.
+func WillRestart(pod *v1.Pod, t *v1.ContainerStateTerminated) bool {
+	switch pod.Spec.RestartPolicy {
+	case v1.RestartPolicyAlways:
+		return true
+	case v1.RestartPolicyOnFailure:
+		return t.ExitCode != 0
+	}
+	return false
+}
+
+// ParsePodStatus returns whether a pod completed (all containers exited with code 0), is ready, started (all containers are running)
+// or neither. A pod can only complete if expectedToComplete is true. An error is returned if a container of the pod could not pull its
+// image, or exited with a non-zero code or when the pod is not expected to complete. Containers that terminated but will be restarted are
+// neither running nor completed.
+func ParsePodStatus(pod *v1.Pod, expectedToComplete bool) (PodStatus, error) {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady && condition.Status == v1.ConditionTrue {
+			return PodStatusReady, nil
+		}
+	}
+	runningCount := 0
+	completedCount := 0
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		t := containerStatus.State.Terminated
+		if t != nil && WillRestart(pod, t) {
+			continue
+		}
+		if t != nil && t.ExitCode == 0 && expectedToComplete {
+			completedCount++
+			continue
+		}
+		if t != nil {
+			return PodStatusOther, fmt.Errorf("aborting because container %s of pod %s terminated (code=%d,signal=%d,reason=%s): %s",
+				containerStatus.Name,
+				pod.ObjectMeta.Name,
+				t.ExitCode,
+				t.Signal,
+				t.Reason,
+				t.Message,
+			)
+		}
+
+		if w := containerStatus.State.Waiting; w != nil && w.Reason == "ErrImagePull" {
+			return PodStatusOther, fmt.Errorf("aborting because container %s of pod %s could not pull image: %s",
+				containerStatus.Name,
+				pod.ObjectMeta.Name,
+				w.Message,
+			)
+		}
+		if containerStatus.State.Running != nil {
+			runningCount++
+		}
+	}
+	if completedCount > 0 && completedCount == len(pod.Status.ContainerStatuses) {
+		return PodStatusCompleted, nil
+	}
+	if runningCount == len(pod.Status.ContainerStatuses) {
+		return PodStatusStarted, nil
+	}
+	return PodStatusOther, nil
+}
+
+// GetTerminatedContainerState returns the state of the first container of pod that terminated and will not be restarted, or nil if no
+// such container exists.
+func GetTerminatedContainerState(pod *v1.Pod) *v1.ContainerStateTerminated {
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		if t := containerStatus.State.Terminated; t != nil && !WillRestart(pod, t) {
+			return t
+		}
+	}
+	return nil
+}
+
+// GroupPodsByService returns the pods of each docker compose service (see AnnotationName), sorted by the number of their replica (see
+// AnnotationReplica) and then by name. The index of a pod, as listed by ps and selected by --index, is its position in the slice plus one.
+func GroupPodsByService(pods []v1.Pod) map[string][]*v1.Pod {
+	podsByService := map[string][]*v1.Pod{}
+	for i := 0; i < len(pods); i++ {
+		pod := &pods[i]
+		name, ok := pod.ObjectMeta.Annotations[AnnotationName]
+		if !ok {
+			continue
+		}
+		podsByService[name] = append(podsByService[name], pod)
+	}
+	for _, servicePods := range podsByService {
+		sort.Slice(servicePods, func(i, j int) bool {
+			// Pods without (a valid) replica annotation, such as pods of workloads, are numbered 0.
+			replicaI, _ := strconv.Atoi(servicePods[i].ObjectMeta.Annotations[AnnotationReplica])
+			replicaJ, _ := strconv.Atoi(servicePods[j].ObjectMeta.Annotations[AnnotationReplica])
+			if replicaI != replicaJ {
+				return replicaI < replicaJ
+			}
+			return servicePods[i].ObjectMeta.Name < servicePods[j].ObjectMeta.Name
+		})
+	}
+	return podsByService
+}
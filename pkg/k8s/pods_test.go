@@ -0,0 +1,112 @@
+package k8s
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func newTestPod(containerStates ...v1.ContainerState) *v1.Pod {
+	pod := &v1.Pod{}
+	pod.ObjectMeta.Name = "web-test123"
+	for _, state := range containerStates {
+		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, v1.ContainerStatus{
+			Name:  "web",
+			State: state,
+		})
+	}
+	return pod
+}
+
+func TestParsePodStatus(t *testing.T) {
+	running := v1.ContainerState{Running: &v1.ContainerStateRunning{}}
+	exited0 := v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 0}}
+	exited1 := v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1}}
+	creating := v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ContainerCreating"}}
+	errImagePull := v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ErrImagePull"}}
+	ready := newTestPod(running)
+	ready.Status.Conditions = []v1.PodCondition{
+		{Type: v1.PodReady, Status: v1.ConditionTrue},
+	}
+	testCases := []struct {
+		name               string
+		pod                *v1.Pod
+		expectedToComplete bool
+		expectedStatus     PodStatus
+		expectedError      bool
+	}{
+		{"NoContainerStatuses", newTestPod(), false, PodStatusStarted, false},
+		{"ContainerCreating", newTestPod(creating), false, PodStatusOther, false},
+		{"Running", newTestPod(running), false, PodStatusStarted, false},
+		{"PartiallyRunning", newTestPod(running, creating), false, PodStatusOther, false},
+		{"Ready", ready, false, PodStatusReady, false},
+		{"Completed", newTestPod(exited0, exited0), true, PodStatusCompleted, false},
+		{"CompletedUnexpectedly", newTestPod(exited0, exited0), false, PodStatusOther, true},
+		{"PartiallyCompleted", newTestPod(exited0, running), true, PodStatusOther, false},
+		{"PartiallyCompletedUnexpectedly", newTestPod(exited0, running), false, PodStatusOther, true},
+		{"Failed", newTestPod(exited0, exited1), true, PodStatusOther, true},
+		{"ErrImagePull", newTestPod(errImagePull), true, PodStatusOther, true},
+	}
+	for _, testCase := range testCases {
+		status, err := ParsePodStatus(testCase.pod, testCase.expectedToComplete)
+		if (err != nil) != testCase.expectedError {
+			t.Errorf("%s: unexpected error %v", testCase.name, err)
+		}
+		if status != testCase.expectedStatus {
+			t.Errorf("%s: expected status %s, but got %s", testCase.name, &testCase.expectedStatus, &status)
+		}
+	}
+}
+
+func newTestServicePod(name, service, replica string) v1.Pod {
+	pod := v1.Pod{}
+	pod.ObjectMeta.Name = name
+	pod.ObjectMeta.Annotations = map[string]string{}
+	if len(service) > 0 {
+		pod.ObjectMeta.Annotations[AnnotationName] = service
+	}
+	if len(replica) > 0 {
+		pod.ObjectMeta.Annotations[AnnotationReplica] = replica
+	}
+	return pod
+}
+
+func TestGroupPodsByService(t *testing.T) {
+	pods := []v1.Pod{
+		newTestServicePod("web-10-env", "web", "10"),
+		newTestServicePod("db-env", "db", ""),
+		newTestServicePod("web-2-env", "web", "2"),
+		newTestServicePod("other", "", ""),
+		newTestServicePod("web-1-env", "web", "1"),
+	}
+	podsByService := GroupPodsByService(pods)
+	if len(podsByService) != 2 {
+		t.Fatalf("expected 2 services, but got %d", len(podsByService))
+	}
+	expected := map[string][]string{
+		"db":  {"db-env"},
+		"web": {"web-1-env", "web-2-env", "web-10-env"},
+	}
+	for service, names := range expected {
+		servicePods := podsByService[service]
+		if len(servicePods) != len(names) {
+			t.Fatalf("service %s: expected %d pods, but got %d", service, len(names), len(servicePods))
+		}
+		for i, name := range names {
+			if servicePods[i].ObjectMeta.Name != name {
+				t.Errorf("service %s: expected pod %s at index %d, but got %s", service, name, i+1, servicePods[i].ObjectMeta.Name)
+			}
+		}
+	}
+}
+
+func TestGroupPodsByServiceWorkload(t *testing.T) {
+	pods := []v1.Pod{
+		newTestServicePod("web-env-1", "web", ""),
+		newTestServicePod("web-env-0", "web", ""),
+	}
+	servicePods := GroupPodsByService(pods)["web"]
+	if len(servicePods) != 2 || servicePods[0].ObjectMeta.Name != "web-env-0" || servicePods[1].ObjectMeta.Name != "web-env-1" {
+		t.Fail()
+	}
+}
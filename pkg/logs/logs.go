@@ -0,0 +1,223 @@
+package logs
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jbrekelmans/kube-compose/pkg/config"
+	k8sUtil "github.com/jbrekelmans/kube-compose/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	clientV1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// Options are the options of the logs command, which mirror those of docker-compose logs.
+type Options struct {
+	Follow     bool
+	Index      int    // if positive, only the logs of the pod with this index of each service are printed, see k8sUtil.GroupPodsByService.
+	Since      string // a duration relative to now (e.g. 10m) or an RFC3339 timestamp.
+	Tail       string // the number of lines to show from the end of the logs of each container, or "all".
+	Timestamps bool
+}
+
+// logSource is a pod whose logs are printed, and the prefix of its lines.
+type logSource struct {
+	pod    *v1.Pod
+	prefix string // the name of the docker compose service, suffixed with the index of the pod if the service has multiple pods.
+}
+
+type logsRunner struct {
+	cfg          *config.Config
+	k8sClientset *kubernetes.Clientset
+	k8sPodClient clientV1.PodInterface
+	mutex        sync.Mutex
+	prefixWidth  int
+}
+
+func (l *logsRunner) initKubernetesClientset() error {
+	k8sClientset, err := kubernetes.NewForConfig(l.cfg.KubeConfig)
+	if err != nil {
+		return err
+	}
+	l.k8sClientset = k8sClientset
+	l.k8sPodClient = l.k8sClientset.CoreV1().Pods(l.cfg.Namespace)
+	return nil
+}
+
+func parsePodLogOptions(opts *Options) (*v1.PodLogOptions, error) {
+	podLogOptions := &v1.PodLogOptions{
+		Follow:     opts.Follow,
+		Timestamps: opts.Timestamps,
+	}
+	if len(opts.Tail) > 0 && opts.Tail != "all" {
+		tailLines, err := strconv.ParseInt(opts.Tail, 10, 64)
+		if err != nil || tailLines < 0 {
+			return nil, fmt.Errorf("tail must be a non-negative integer or \"all\", but got %#v", opts.Tail)
+		}
+		podLogOptions.TailLines = &tailLines
+	}
+	if len(opts.Since) > 0 {
+		if duration, err := time.ParseDuration(opts.Since); err == nil {
+			sinceSeconds := int64(duration.Seconds())
+			if sinceSeconds < 1 {
+				sinceSeconds = 1
+			}
+			podLogOptions.SinceSeconds = &sinceSeconds
+		} else if t, err := time.Parse(time.RFC3339, opts.Since); err == nil {
+			sinceTime := metav1.NewTime(t)
+			podLogOptions.SinceTime = &sinceTime
+		} else {
+			return nil, fmt.Errorf("since must be a duration (e.g. 10m) or an RFC3339 timestamp, but got %#v", opts.Since)
+		}
+	}
+	return podLogOptions, nil
+}
+
+// getLogSources returns the pods of the selected docker compose services (all services by default), sorted by service name and index.
+// If index is positive only the pod with that index of each service is returned.
+func (l *logsRunner) getLogSources(index int) ([]*logSource, error) {
+	names := l.cfg.Services
+	if len(names) > 0 {
+		for _, name := range names {
+			if _, ok := l.cfg.CanonicalComposeFile.Services[name]; !ok {
+				return nil, fmt.Errorf("no service named %#v exists", name)
+			}
+		}
+	} else {
+		for name := range l.cfg.CanonicalComposeFile.Services {
+			names = append(names, name)
+		}
+	}
+	names = append([]string{}, names...)
+	sort.Strings(names)
+	listOptions := metav1.ListOptions{
+		LabelSelector: l.cfg.EnvironmentLabel + "=" + l.cfg.EnvironmentID,
+	}
+	podList, err := l.k8sPodClient.List(context.Background(), listOptions)
+	if err != nil {
+		return nil, err
+	}
+	podsByService := k8sUtil.GroupPodsByService(podList.Items)
+	logSources := []*logSource{}
+	for _, name := range names {
+		pods := podsByService[name]
+		if index > len(pods) {
+			return nil, fmt.Errorf("app %s: no pod with index %d found, the service has %d pods", name, index, len(pods))
+		}
+		for i, pod := range pods {
+			if index > 0 && i+1 != index {
+				continue
+			}
+			prefix := name
+			if len(pods) > 1 {
+				prefix = fmt.Sprintf("%s_%d", name, i+1)
+			}
+			logSources = append(logSources, &logSource{
+				pod:    pod,
+				prefix: prefix,
+			})
+		}
+	}
+	return logSources, nil
+}
+
+func (l *logsRunner) printLine(prefix, line string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	fmt.Printf("%-*s | %s\n", l.prefixWidth, prefix, line)
+}
+
+// hasStarted returns whether a container of pod has started, because getting the logs of a pod fails while all of its containers are
+// waiting (e.g. ContainerCreating).
+func hasStarted(pod *v1.Pod) bool {
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		if containerStatus.State.Running != nil || containerStatus.State.Terminated != nil ||
+			containerStatus.LastTerminationState.Terminated != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// streamLogs copies the logs of the (single) container of the pod of source to stdout, prefixing each line with the prefix of source.
+// Pods that have not started are skipped, so that they do not prevent printing the logs of other pods.
+func (l *logsRunner) streamLogs(source *logSource, podLogOptions *v1.PodLogOptions) error {
+	pod := source.pod
+	name := pod.ObjectMeta.Annotations[k8sUtil.AnnotationName]
+	if !hasStarted(pod) {
+		fmt.Printf("app %s: skipping pod %s because it has not started yet (phase %s)\n", name, pod.ObjectMeta.Name, pod.Status.Phase)
+		return nil
+	}
+	stream, err := l.k8sPodClient.GetLogs(pod.ObjectMeta.Name, podLogOptions).Stream(context.Background())
+	if err != nil {
+		return fmt.Errorf("app %s: error while getting logs of pod %s: %v", name, pod.ObjectMeta.Name, err)
+	}
+	defer stream.Close()
+	reader := bufio.NewReader(stream)
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			if line[len(line)-1] == '\n' {
+				line = line[:len(line)-1]
+			}
+			l.printLine(source.prefix, line)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("app %s: error while reading logs of pod %s: %v", name, pod.ObjectMeta.Name, err)
+		}
+	}
+}
+
+func (l *logsRunner) run(opts *Options) error {
+	if opts.Index < 0 {
+		return fmt.Errorf("index must be positive, but got %d", opts.Index)
+	}
+	podLogOptions, err := parsePodLogOptions(opts)
+	if err != nil {
+		return err
+	}
+	err = l.initKubernetesClientset()
+	if err != nil {
+		return err
+	}
+	logSources, err := l.getLogSources(opts.Index)
+	if err != nil {
+		return err
+	}
+	for _, source := range logSources {
+		if n := len(source.prefix); n > l.prefixWidth {
+			l.prefixWidth = n
+		}
+	}
+	// Each pod sends exactly one (possibly nil) error, so that the first error is returned as soon as it occurs, also when following.
+	errorChannel := make(chan error, len(logSources))
+	for _, source := range logSources {
+		go func(source *logSource) {
+			errorChannel <- l.streamLogs(source, podLogOptions)
+		}(source)
+	}
+	for i := 0; i < len(logSources); i++ {
+		if err := <-errorChannel; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run runs a docker-compose logs command, which multiplexes the logs of the pods of the environment to stdout.
+func Run(cfg *config.Config, opts *Options) error {
+	l := &logsRunner{
+		cfg: cfg,
+	}
+	return l.run(opts)
+}
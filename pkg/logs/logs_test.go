@@ -0,0 +1,103 @@
+package logs
+
+import (
+	"testing"
+	"time"
+
+	k8sUtil "github.com/jbrekelmans/kube-compose/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParsePodLogOptions(t *testing.T) {
+	sinceTime, _ := time.Parse(time.RFC3339, "2019-05-01T10:00:00Z")
+	testCases := []struct {
+		opts         Options
+		tailLines    int64 // -1 if TailLines must be nil
+		sinceSeconds int64 // -1 if SinceSeconds must be nil
+		sinceTime    time.Time
+		err          bool
+	}{
+		{opts: Options{}, tailLines: -1, sinceSeconds: -1},
+		{opts: Options{Tail: "all"}, tailLines: -1, sinceSeconds: -1},
+		{opts: Options{Tail: "10"}, tailLines: 10, sinceSeconds: -1},
+		{opts: Options{Tail: "-1"}, err: true},
+		{opts: Options{Tail: "x"}, err: true},
+		{opts: Options{Since: "10m"}, tailLines: -1, sinceSeconds: 600},
+		{opts: Options{Since: "100ms"}, tailLines: -1, sinceSeconds: 1},
+		{opts: Options{Since: "2019-05-01T10:00:00Z"}, tailLines: -1, sinceSeconds: -1, sinceTime: sinceTime},
+		{opts: Options{Since: "yesterday"}, err: true},
+	}
+	for _, testCase := range testCases {
+		podLogOptions, err := parsePodLogOptions(&testCase.opts)
+		if testCase.err {
+			if err == nil {
+				t.Errorf("%#v: expected an error", testCase.opts)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%#v: %v", testCase.opts, err)
+			continue
+		}
+		if (podLogOptions.TailLines == nil) != (testCase.tailLines < 0) ||
+			(podLogOptions.TailLines != nil && *podLogOptions.TailLines != testCase.tailLines) {
+			t.Errorf("%#v: unexpected TailLines %v", testCase.opts, podLogOptions.TailLines)
+		}
+		if (podLogOptions.SinceSeconds == nil) != (testCase.sinceSeconds < 0) ||
+			(podLogOptions.SinceSeconds != nil && *podLogOptions.SinceSeconds != testCase.sinceSeconds) {
+			t.Errorf("%#v: unexpected SinceSeconds %v", testCase.opts, podLogOptions.SinceSeconds)
+		}
+		if (podLogOptions.SinceTime == nil) != testCase.sinceTime.IsZero() ||
+			(podLogOptions.SinceTime != nil && !podLogOptions.SinceTime.Time.Equal(testCase.sinceTime)) {
+			t.Errorf("%#v: unexpected SinceTime %v", testCase.opts, podLogOptions.SinceTime)
+		}
+	}
+}
+
+func TestParsePodLogOptionsFollowTimestamps(t *testing.T) {
+	podLogOptions, err := parsePodLogOptions(&Options{Follow: true, Timestamps: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !podLogOptions.Follow || !podLogOptions.Timestamps {
+		t.Fatalf("%#v", podLogOptions)
+	}
+}
+
+func TestStreamLogsSkipsPodsThatHaveNotStarted(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	l := &logsRunner{
+		k8sPodClient: clientset.CoreV1().Pods("default"),
+	}
+	pod := &v1.Pod{}
+	pod.ObjectMeta.Name = "web-test123"
+	pod.ObjectMeta.Annotations = map[string]string{
+		k8sUtil.AnnotationName: "web",
+	}
+	pod.Status.Phase = v1.PodPending
+	pod.Status.ContainerStatuses = []v1.ContainerStatus{
+		{
+			Name: "web",
+			State: v1.ContainerState{
+				Waiting: &v1.ContainerStateWaiting{Reason: "ContainerCreating"},
+			},
+		},
+	}
+	err := l.streamLogs(&logSource{pod: pod, prefix: "web"}, &v1.PodLogOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actions := clientset.Actions(); len(actions) > 0 {
+		t.Fatalf("expected the logs of the pod not to be requested, but got %v", actions)
+	}
+
+	pod.Status.ContainerStatuses[0].State = v1.ContainerState{Running: &v1.ContainerStateRunning{}}
+	err = l.streamLogs(&logSource{pod: pod, prefix: "web"}, &v1.PodLogOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actions := clientset.Actions(); len(actions) != 1 || actions[0].GetSubresource() != "log" {
+		t.Fatalf("expected the logs of the pod to be requested, but got %v", actions)
+	}
+}
@@ -0,0 +1,223 @@
+package ps
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jbrekelmans/kube-compose/pkg/config"
+	k8sUtil "github.com/jbrekelmans/kube-compose/pkg/k8s"
+	yaml "gopkg.in/yaml.v2"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	clientV1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	OutputFormatJSON  = "json"
+	OutputFormatTable = "table"
+	OutputFormatYAML  = "yaml"
+)
+
+// ServiceStatus is the state of a pod and the Kubernetes service of a docker compose service. Services with multiple pods (e.g. replicas)
+// have one ServiceStatus per pod, and services without pods have a single ServiceStatus without pod.
+type ServiceStatus struct {
+	ClusterIP string   `json:"clusterIP,omitempty" yaml:"clusterIP,omitempty"`
+	ExitCode  *int32   `json:"exitCode,omitempty" yaml:"exitCode,omitempty"`
+	Index     int      `json:"index,omitempty" yaml:"index,omitempty"` // the index of the pod, see k8sUtil.GroupPodsByService.
+	Phase     string   `json:"phase,omitempty" yaml:"phase,omitempty"`
+	Pod       string   `json:"pod,omitempty" yaml:"pod,omitempty"`
+	Ports     []string `json:"ports,omitempty" yaml:"ports,omitempty"`
+	Ready     string   `json:"ready,omitempty" yaml:"ready,omitempty"`
+	Restarts  int32    `json:"restarts" yaml:"restarts"`
+	Service   string   `json:"service" yaml:"service"`
+}
+
+type psRunner struct {
+	cfg              *config.Config
+	k8sClientset     *kubernetes.Clientset
+	k8sServiceClient clientV1.ServiceInterface
+	k8sPodClient     clientV1.PodInterface
+}
+
+func (p *psRunner) initKubernetesClientset() error {
+	k8sClientset, err := kubernetes.NewForConfig(p.cfg.KubeConfig)
+	if err != nil {
+		return err
+	}
+	p.k8sClientset = k8sClientset
+	p.k8sServiceClient = p.k8sClientset.CoreV1().Services(p.cfg.Namespace)
+	p.k8sPodClient = p.k8sClientset.CoreV1().Pods(p.cfg.Namespace)
+	return nil
+}
+
+// getServiceNames returns the names of the docker compose services selected on the command line (all services by default), sorted.
+func (p *psRunner) getServiceNames() ([]string, error) {
+	if len(p.cfg.Services) > 0 {
+		for _, name := range p.cfg.Services {
+			if _, ok := p.cfg.CanonicalComposeFile.Services[name]; !ok {
+				return nil, fmt.Errorf("no service named %#v exists", name)
+			}
+		}
+		names := append([]string{}, p.cfg.Services...)
+		sort.Strings(names)
+		return names, nil
+	}
+	names := make([]string, 0, len(p.cfg.CanonicalComposeFile.Services))
+	for name := range p.cfg.CanonicalComposeFile.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func updateServiceStatusFromPod(serviceStatus *ServiceStatus, pod *v1.Pod) {
+	serviceStatus.Pod = pod.ObjectMeta.Name
+	serviceStatus.Phase = string(pod.Status.Phase)
+	// ps reports containers that exited with code 0 as completed, regardless of whether up expects their service to complete.
+	podStatus, err := k8sUtil.ParsePodStatus(pod, true)
+	if err != nil {
+		// The pod failed, which is already reflected by the phase and exit code.
+		podStatus = k8sUtil.PodStatusOther
+	}
+	serviceStatus.Ready = podStatus.String()
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		serviceStatus.Restarts += containerStatus.RestartCount
+		t := containerStatus.State.Terminated
+		if t == nil {
+			t = containerStatus.LastTerminationState.Terminated
+		}
+		if t != nil {
+			exitCode := t.ExitCode
+			serviceStatus.ExitCode = &exitCode
+		}
+	}
+}
+
+func updateServiceStatusFromService(serviceStatus *ServiceStatus, service *v1.Service) {
+	serviceStatus.ClusterIP = service.Spec.ClusterIP
+	serviceStatus.Ports = make([]string, len(service.Spec.Ports))
+	for i, port := range service.Spec.Ports {
+		serviceStatus.Ports[i] = fmt.Sprintf("%d/%s", port.Port, port.Protocol)
+	}
+}
+
+func (p *psRunner) getServiceStatuses() ([]*ServiceStatus, error) {
+	names, err := p.getServiceNames()
+	if err != nil {
+		return nil, err
+	}
+	listOptions := metav1.ListOptions{
+		LabelSelector: p.cfg.EnvironmentLabel + "=" + p.cfg.EnvironmentID,
+	}
+	podList, err := p.k8sPodClient.List(context.Background(), listOptions)
+	if err != nil {
+		return nil, err
+	}
+	podsByService := k8sUtil.GroupPodsByService(podList.Items)
+	serviceList, err := p.k8sServiceClient.List(context.Background(), listOptions)
+	if err != nil {
+		return nil, err
+	}
+	services := map[string]*v1.Service{}
+	for i := 0; i < len(serviceList.Items); i++ {
+		service := &serviceList.Items[i]
+		if name, ok := service.ObjectMeta.Annotations[k8sUtil.AnnotationName]; ok {
+			services[name] = service
+		}
+	}
+	serviceStatuses := []*ServiceStatus{}
+	for _, name := range names {
+		pods := podsByService[name]
+		n := len(pods)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			serviceStatus := &ServiceStatus{
+				Service: name,
+			}
+			if i < len(pods) {
+				serviceStatus.Index = i + 1
+				updateServiceStatusFromPod(serviceStatus, pods[i])
+			}
+			if service := services[name]; service != nil {
+				updateServiceStatusFromService(serviceStatus, service)
+			}
+			serviceStatuses = append(serviceStatuses, serviceStatus)
+		}
+	}
+	return serviceStatuses, nil
+}
+
+func printTable(out io.Writer, serviceStatuses []*ServiceStatus) error {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "SERVICE\tINDEX\tPOD\tPHASE\tREADY\tRESTARTS\tEXIT CODE\tCLUSTER IP\tPORTS")
+	for _, serviceStatus := range serviceStatuses {
+		index := ""
+		if serviceStatus.Index > 0 {
+			index = strconv.Itoa(serviceStatus.Index)
+		}
+		exitCode := ""
+		if serviceStatus.ExitCode != nil {
+			exitCode = strconv.Itoa(int(*serviceStatus.ExitCode))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			serviceStatus.Service,
+			index,
+			serviceStatus.Pod,
+			serviceStatus.Phase,
+			serviceStatus.Ready,
+			serviceStatus.Restarts,
+			exitCode,
+			serviceStatus.ClusterIP,
+			strings.Join(serviceStatus.Ports, ","),
+		)
+	}
+	return w.Flush()
+}
+
+func (p *psRunner) run(outputFormat string) error {
+	err := p.initKubernetesClientset()
+	if err != nil {
+		return err
+	}
+	serviceStatuses, err := p.getServiceStatuses()
+	if err != nil {
+		return err
+	}
+	switch outputFormat {
+	case OutputFormatJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(serviceStatuses)
+	case OutputFormatYAML:
+		bytes, err := yaml.Marshal(serviceStatuses)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(bytes)
+		return err
+	}
+	return printTable(os.Stdout, serviceStatuses)
+}
+
+// Run runs a docker-compose ps command, printing the state of each pod of each docker compose service in the specified output format.
+func Run(cfg *config.Config, outputFormat string) error {
+	switch outputFormat {
+	case OutputFormatJSON, OutputFormatTable, OutputFormatYAML:
+	default:
+		return fmt.Errorf("unsupported output format %#v, must be one of %s, %s and %s", outputFormat, OutputFormatJSON, OutputFormatTable, OutputFormatYAML)
+	}
+	p := &psRunner{
+		cfg: cfg,
+	}
+	return p.run(outputFormat)
+}
@@ -0,0 +1,122 @@
+package ps
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jbrekelmans/kube-compose/pkg/config"
+	k8sUtil "github.com/jbrekelmans/kube-compose/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestPSRunner(t *testing.T, objects ...*v1.Pod) *psRunner {
+	cfg := &config.Config{
+		CanonicalComposeFile: config.CanonicalComposeFile{
+			Services: map[string]*config.Service{
+				"db":      {},
+				"web":     {},
+				"migrate": {},
+			},
+		},
+		EnvironmentID:    "test123",
+		EnvironmentLabel: config.DefaultEnvironmentLabel,
+		Namespace:        "default",
+	}
+	clientset := fake.NewSimpleClientset()
+	p := &psRunner{
+		cfg:              cfg,
+		k8sPodClient:     clientset.CoreV1().Pods(cfg.Namespace),
+		k8sServiceClient: clientset.CoreV1().Services(cfg.Namespace),
+	}
+	for _, pod := range objects {
+		if _, err := p.k8sPodClient.Create(context.Background(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	service := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "db-test123",
+			Annotations: map[string]string{k8sUtil.AnnotationName: "db"},
+			Labels:      map[string]string{cfg.EnvironmentLabel: cfg.EnvironmentID},
+		},
+		Spec: v1.ServiceSpec{
+			ClusterIP: "10.0.0.1",
+			Ports: []v1.ServicePort{
+				{Port: 5432, Protocol: v1.ProtocolTCP},
+			},
+		},
+	}
+	if _, err := p.k8sServiceClient.Create(context.Background(), service, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func newTestPod(name, service string, status v1.PodStatus) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Annotations: map[string]string{k8sUtil.AnnotationName: service},
+			Labels:      map[string]string{config.DefaultEnvironmentLabel: "test123"},
+		},
+		Status: status,
+	}
+}
+
+func TestGetServiceStatuses(t *testing.T) {
+	p := newTestPSRunner(t,
+		newTestPod("db-test123", "db", v1.PodStatus{
+			Phase:      v1.PodRunning,
+			Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}},
+			ContainerStatuses: []v1.ContainerStatus{
+				{
+					Name:                 "db",
+					RestartCount:         2,
+					State:                v1.ContainerState{Running: &v1.ContainerStateRunning{}},
+					LastTerminationState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 137}},
+				},
+			},
+		}),
+		newTestPod("web-2-test123", "web", v1.PodStatus{Phase: v1.PodPending}),
+		newTestPod("web-1-test123", "web", v1.PodStatus{Phase: v1.PodPending}),
+	)
+	serviceStatuses, err := p.getServiceStatuses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	err = printTable(&out, serviceStatuses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{
+		{"SERVICE", "INDEX", "POD", "PHASE", "READY", "RESTARTS", "EXIT", "CODE", "CLUSTER", "IP", "PORTS"},
+		{"db", "1", "db-test123", "Running", "ready", "2", "137", "10.0.0.1", "5432/TCP"},
+		{"migrate", "0"},
+		{"web", "1", "web-1-test123", "Pending", "started", "0"},
+		{"web", "2", "web-2-test123", "Pending", "started", "0"},
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, but got:\n%s", len(expected), out.String())
+	}
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if strings.Join(fields, " ") != strings.Join(expected[i], " ") {
+			t.Errorf("line %d: expected %v, but got %v", i+1, expected[i], fields)
+		}
+	}
+}
+
+func TestGetServiceStatusesUnknownService(t *testing.T) {
+	p := newTestPSRunner(t)
+	p.cfg.Services = []string{"cache"}
+	_, err := p.getServiceStatuses()
+	if err == nil {
+		t.Fail()
+	}
+}
@@ -0,0 +1,85 @@
+package up
+
+import (
+	"fmt"
+
+	k8sUtil "github.com/jbrekelmans/kube-compose/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+	k8sError "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// newAnchor returns the anchor ConfigMap of the environment, which holds the resolved docker compose file and owns all other resources
+// of the environment, so that down can delete the environment by deleting the anchor.
+func (u *upRunner) newAnchor() *v1.ConfigMap {
+	anchor := &v1.ConfigMap{
+		Data: map[string]string{
+			k8sUtil.AnchorDataKey: string(u.cfg.ResolvedComposeFile),
+		},
+	}
+	anchor.ObjectMeta.Name = k8sUtil.AnchorName(u.cfg.EnvironmentID)
+	anchor.ObjectMeta.Labels = map[string]string{
+		u.cfg.EnvironmentLabel: u.cfg.EnvironmentID,
+	}
+	u.stampGCAnnotations(&anchor.ObjectMeta)
+	return anchor
+}
+
+// createAnchor creates the anchor ConfigMap of the environment, so that resources can reference it as their owner. If the anchor already
+// exists (e.g. because up was run before), up updates it (see updateAnchor). run does not, so that the anchor keeps the TTL of the
+// environment (see inheritAnchorTTL).
+func (u *upRunner) createAnchor() error {
+	anchor, err := u.k8sConfigMapClient.Create(u.ctx, u.newAnchor(), metav1.CreateOptions{})
+	if k8sError.IsAlreadyExists(err) {
+		anchor, err = u.k8sConfigMapClient.Get(u.ctx, k8sUtil.AnchorName(u.cfg.EnvironmentID), metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if u.opts == nil {
+			fmt.Printf("anchor config map %s already exists\n", anchor.ObjectMeta.Name)
+		} else {
+			anchor, err = u.updateAnchor(anchor)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("updated anchor config map %s\n", anchor.ObjectMeta.Name)
+		}
+	} else if err != nil {
+		return err
+	} else {
+		fmt.Printf("created anchor config map %s\n", anchor.ObjectMeta.Name)
+	}
+	u.anchor = anchor
+	return nil
+}
+
+// updateAnchor replaces the resolved docker compose file and the annotations of gc of the existing anchor by those of this run of up, so
+// that the anchor describes the environment as it was last brought up.
+func (u *upRunner) updateAnchor(anchor *v1.ConfigMap) (*v1.ConfigMap, error) {
+	anchor.Data = u.newAnchor().Data
+	for _, key := range []string{k8sUtil.AnnotationCIJob, k8sUtil.AnnotationCreated, k8sUtil.AnnotationTTL} {
+		delete(anchor.ObjectMeta.Annotations, key)
+	}
+	u.stampGCAnnotations(&anchor.ObjectMeta)
+	return u.k8sConfigMapClient.Update(u.ctx, anchor, metav1.UpdateOptions{})
+}
+
+// stampObjectMeta stamps the object metadata of a resource of the environment with the annotations of gc (see stampGCAnnotations), and
+// an owner reference to the anchor of the environment. Rendered resources have no owner, because the anchor does not exist yet.
+func (u *upRunner) stampObjectMeta(objectMeta *metav1.ObjectMeta) {
+	u.stampGCAnnotations(objectMeta)
+	if u.anchor == nil {
+		return
+	}
+	// down deletes the anchor with foreground propagation, which waits until dependents that block owner deletion are deleted.
+	blockOwnerDeletion := true
+	objectMeta.OwnerReferences = []metav1.OwnerReference{
+		{
+			APIVersion:         "v1",
+			BlockOwnerDeletion: &blockOwnerDeletion,
+			Kind:               "ConfigMap",
+			Name:               u.anchor.ObjectMeta.Name,
+			UID:                u.anchor.ObjectMeta.UID,
+		},
+	}
+}
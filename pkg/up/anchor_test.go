@@ -0,0 +1,142 @@
+package up
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	k8sUtil "github.com/jbrekelmans/kube-compose/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNewAnchor(t *testing.T) {
+	u := newTestUpRunner(t, "version: '2.4'\nservices:\n  web:\n    image: nginx\n")
+	u.initGCAnnotations(time.Hour, "")
+	anchor := u.newAnchor()
+	if anchor.ObjectMeta.Name != k8sUtil.AnchorName(testEnvironmentID) {
+		t.Errorf("unexpected name %s", anchor.ObjectMeta.Name)
+	}
+	if anchor.ObjectMeta.Labels[u.cfg.EnvironmentLabel] != testEnvironmentID {
+		t.Errorf("expected the environment label, but got %v", anchor.ObjectMeta.Labels)
+	}
+	if anchor.Data[k8sUtil.AnchorDataKey] != string(u.cfg.ResolvedComposeFile) {
+		t.Errorf("expected the resolved docker compose file, but got %#v", anchor.Data[k8sUtil.AnchorDataKey])
+	}
+	if anchor.ObjectMeta.Annotations[k8sUtil.AnnotationTTL] != "1h0m0s" {
+		t.Errorf("expected a TTL annotation, but got %v", anchor.ObjectMeta.Annotations)
+	}
+}
+
+func TestStampObjectMetaRendered(t *testing.T) {
+	u := &upRunner{}
+	objectMeta := &metav1.ObjectMeta{}
+	u.stampObjectMeta(objectMeta)
+	if len(objectMeta.Annotations) > 0 || len(objectMeta.OwnerReferences) > 0 {
+		t.Errorf("expected rendered resources not to be stamped, but got %+v", objectMeta)
+	}
+}
+
+func TestStampObjectMeta(t *testing.T) {
+	anchor := &v1.ConfigMap{}
+	anchor.ObjectMeta.Name = k8sUtil.AnchorName(testEnvironmentID)
+	anchor.ObjectMeta.UID = types.UID("1234")
+	u := &upRunner{
+		anchor: anchor,
+	}
+	u.initGCAnnotations(0, "42")
+	objectMeta := &metav1.ObjectMeta{
+		Annotations: map[string]string{
+			k8sUtil.AnnotationName: "web",
+		},
+	}
+	u.stampObjectMeta(objectMeta)
+	if objectMeta.Annotations[k8sUtil.AnnotationName] != "web" {
+		t.Error("expected existing annotations to be kept")
+	}
+	if _, ok := objectMeta.Annotations[k8sUtil.AnnotationCreated]; !ok {
+		t.Error("expected a created annotation")
+	}
+	if objectMeta.Annotations[k8sUtil.AnnotationCIJob] != "42" {
+		t.Errorf("expected a CI job annotation, but got %v", objectMeta.Annotations)
+	}
+	if len(objectMeta.OwnerReferences) != 1 {
+		t.Fatalf("expected 1 owner, but got %d", len(objectMeta.OwnerReferences))
+	}
+	owner := objectMeta.OwnerReferences[0]
+	if owner.Kind != "ConfigMap" || owner.Name != anchor.ObjectMeta.Name || owner.UID != anchor.ObjectMeta.UID {
+		t.Errorf("expected the anchor to be the owner, but got %+v", owner)
+	}
+	if owner.BlockOwnerDeletion == nil || !*owner.BlockOwnerDeletion {
+		t.Error("expected the owner reference to block owner deletion")
+	}
+}
+
+// newTestExistingAnchor returns an upRunner whose namespace contains the anchor of an environment that was brought up with TTL 2h by CI
+// job 1, and with another docker compose file.
+func newTestExistingAnchor(t *testing.T) *upRunner {
+	u := newTestUpRunner(t, "version: '2.4'\nservices:\n  web:\n    image: nginx\n")
+	anchor := &v1.ConfigMap{
+		Data: map[string]string{
+			k8sUtil.AnchorDataKey: "old",
+		},
+	}
+	anchor.ObjectMeta.Name = k8sUtil.AnchorName(testEnvironmentID)
+	anchor.ObjectMeta.Namespace = u.cfg.Namespace
+	anchor.ObjectMeta.Annotations = map[string]string{
+		k8sUtil.AnnotationCIJob:   "1",
+		k8sUtil.AnnotationCreated: "2026-10-16T12:00:00Z",
+		k8sUtil.AnnotationTTL:     "2h0m0s",
+	}
+	u.k8sConfigMapClient = fake.NewSimpleClientset(anchor).CoreV1().ConfigMaps(u.cfg.Namespace)
+	return u
+}
+
+func TestCreateAnchorUpdatesExistingAnchor(t *testing.T) {
+	u := newTestExistingAnchor(t)
+	u.opts = &Options{}
+	u.initGCAnnotations(0, "42")
+	err := u.createAnchor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	anchor, err := u.k8sConfigMapClient.Get(context.Background(), k8sUtil.AnchorName(testEnvironmentID), metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if anchor.Data[k8sUtil.AnchorDataKey] != string(u.cfg.ResolvedComposeFile) {
+		t.Errorf("expected the resolved docker compose file, but got %#v", anchor.Data[k8sUtil.AnchorDataKey])
+	}
+	annotations := anchor.ObjectMeta.Annotations
+	if annotations[k8sUtil.AnnotationCIJob] != "42" || annotations[k8sUtil.AnnotationCreated] != u.gcAnnotations[k8sUtil.AnnotationCreated] {
+		t.Errorf("expected the annotations of gc of up, but got %v", annotations)
+	}
+	if _, ok := annotations[k8sUtil.AnnotationTTL]; ok {
+		t.Errorf("expected up without a TTL to remove the TTL, but got %v", annotations)
+	}
+	if u.anchor.ObjectMeta.Annotations[k8sUtil.AnnotationCIJob] != "42" {
+		t.Errorf("expected resources to be owned by the updated anchor, but got %+v", u.anchor)
+	}
+}
+
+func TestCreateAnchorRunKeepsExistingAnchor(t *testing.T) {
+	u := newTestExistingAnchor(t)
+	u.initGCAnnotations(0, "")
+	err := u.createAnchor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.inheritAnchorTTL()
+	anchor, err := u.k8sConfigMapClient.Get(context.Background(), k8sUtil.AnchorName(testEnvironmentID), metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if anchor.Data[k8sUtil.AnchorDataKey] != "old" || anchor.ObjectMeta.Annotations[k8sUtil.AnnotationTTL] != "2h0m0s" {
+		t.Errorf("expected run not to update the anchor, but got %+v", anchor)
+	}
+	if ttl := u.gcAnnotations[k8sUtil.AnnotationTTL]; ttl != "2h0m0s" {
+		t.Errorf("expected the TTL of the anchor, but got %#v", ttl)
+	}
+}
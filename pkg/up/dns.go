@@ -0,0 +1,80 @@
+package up
+
+import (
+	"fmt"
+
+	"github.com/jbrekelmans/kube-compose/pkg/config"
+	v1 "k8s.io/api/core/v1"
+	k8sError "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// subdomain returns the name of the headless service of the environment. Names of resources of apps never contain a hyphen before the
+// environment ID suffix (see EncodeName), so this name cannot conflict with them.
+func (u *upRunner) subdomain() string {
+	return "kube-compose-" + u.cfg.EnvironmentID
+}
+
+// needsHeadlessService returns true if the headless service of the environment is created, because names are resolved via cluster DNS
+// or because it is the governing service of StatefulSets. The per-app services of StatefulSet apps are not headless and only exist if the
+// app has ports.
+func (u *upRunner) needsHeadlessService() bool {
+	if u.cfg.DNS.Mode == config.DNSModeSubdomain {
+		return true
+	}
+	for _, app := range u.apps {
+		if u.cfg.CanonicalComposeFile.Services[app.name].Kind == config.KindStatefulSet {
+			return true
+		}
+	}
+	return false
+}
+
+// newHeadlessService returns the headless service that governs the subdomain of all pods of the environment. Cluster DNS resolves
+// <hostname>.<subdomain>.<namespace>.svc.<cluster domain> to the IP of the pod with that hostname and subdomain.
+// https://kubernetes.io/docs/concepts/services-networking/dns-pod-service/#pod-s-hostname-and-subdomain-fields
+func (u *upRunner) newHeadlessService() *v1.Service {
+	service := &v1.Service{
+		Spec: v1.ServiceSpec{
+			ClusterIP: v1.ClusterIPNone,
+			// Like docker-compose, names should resolve before containers are healthy.
+			PublishNotReadyAddresses: true,
+			Selector: map[string]string{
+				u.cfg.EnvironmentLabel: u.cfg.EnvironmentID,
+			},
+		},
+	}
+	service.ObjectMeta.Name = u.subdomain()
+	service.ObjectMeta.Labels = map[string]string{
+		u.cfg.EnvironmentLabel: u.cfg.EnvironmentID,
+	}
+	u.stampObjectMeta(&service.ObjectMeta)
+	return service
+}
+
+func (u *upRunner) createHeadlessService() error {
+	service := u.newHeadlessService()
+	_, err := u.k8sServiceClient.Create(u.ctx, service, metav1.CreateOptions{})
+	if k8sError.IsAlreadyExists(err) {
+		fmt.Printf("headless service %s already exists\n", service.ObjectMeta.Name)
+	} else if err != nil {
+		return err
+	} else {
+		fmt.Printf("created headless service %s\n", service.ObjectMeta.Name)
+	}
+	return nil
+}
+
+// initPodDNS makes the names of apps resolvable by the pod of app when names are resolved via cluster DNS.
+func (u *upRunner) initPodDNS(app *app, podSpec *v1.PodSpec) {
+	if u.cfg.DNS.Mode != config.DNSModeSubdomain {
+		return
+	}
+	podSpec.Hostname = app.name
+	podSpec.Subdomain = u.subdomain()
+	podSpec.DNSConfig = &v1.PodDNSConfig{
+		Searches: []string{
+			fmt.Sprintf("%s.%s.svc.%s", u.subdomain(), u.cfg.Namespace, u.cfg.DNS.ClusterDomain),
+		},
+	}
+}
@@ -0,0 +1,42 @@
+package up
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestInitPodDNSSubdomain(t *testing.T) {
+	u := newTestUpRunner(t, `version: '2.4'
+services:
+  web:
+    image: nginx
+x-kube-compose:
+  dns:
+    mode: subdomain
+    cluster_domain: example.local
+`)
+	podSpec := &v1.PodSpec{}
+	u.initPodDNS(u.apps["web"], podSpec)
+	if podSpec.Hostname != "web" {
+		t.Errorf("expected hostname web, but got %#v", podSpec.Hostname)
+	}
+	if podSpec.Subdomain != "kube-compose-"+testEnvironmentID {
+		t.Errorf("unexpected subdomain %#v", podSpec.Subdomain)
+	}
+	if podSpec.DNSConfig == nil || len(podSpec.DNSConfig.Searches) != 1 {
+		t.Fatalf("unexpected DNS config %+v", podSpec.DNSConfig)
+	}
+	if search := podSpec.DNSConfig.Searches[0]; search != "kube-compose-test123.default.svc.example.local" {
+		t.Errorf("unexpected DNS search %#v", search)
+	}
+}
+
+func TestInitPodDNSHostAliases(t *testing.T) {
+	u := newTestUpRunner(t, "version: '2.4'\nservices:\n  web:\n    image: nginx\n")
+	podSpec := &v1.PodSpec{}
+	u.initPodDNS(u.apps["web"], podSpec)
+	if podSpec.Hostname != "" || podSpec.Subdomain != "" || podSpec.DNSConfig != nil {
+		t.Errorf("expected pod spec to be unchanged in host_aliases mode, but got %+v", podSpec)
+	}
+}
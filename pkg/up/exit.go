@@ -0,0 +1,142 @@
+package up
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jbrekelmans/kube-compose/pkg/config"
+	k8sUtil "github.com/jbrekelmans/kube-compose/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+)
+
+// Options are the options of up.
+type Options struct {
+	// AbortOnContainerExit makes up wait for containers to exit instead of returning once all pods are ready, like docker-compose's
+	// --abort-on-container-exit. The run is aborted when a container exits, except if it exits with code 0 and its service is expected to
+	// complete (see isExpectedToComplete), so that services like migrations can run to completion.
+	AbortOnContainerExit bool
+	// ExitCodeFrom is the name of a service whose logs are streamed and whose exit code is returned when its container exits. Implies
+	// AbortOnContainerExit.
+	ExitCodeFrom string
+	// MaxRestarts is the number of times a container may be restarted according to the restart policy of its service. The run is
+	// aborted if the container terminates again, so that containers in a crash loop fail the run.
+	MaxRestarts int
+	// TTL is the duration after which kube-compose gc deletes the environment, or zero if the environment does not expire.
+	TTL time.Duration
+	// CIJobID is the id of the CI job that runs up, so that kube-compose gc can delete the environment once the job is gone.
+	CIJobID string
+}
+
+func (u *upRunner) initExitCodeFrom() error {
+	if len(u.opts.ExitCodeFrom) == 0 {
+		return nil
+	}
+	u.opts.AbortOnContainerExit = true
+	app := u.apps[u.opts.ExitCodeFrom]
+	if app == nil {
+		return fmt.Errorf("no service named %#v exists", u.opts.ExitCodeFrom)
+	}
+	if !u.appsWithoutPods[app] {
+		return fmt.Errorf("the service %s must be started to get its exit code", app.name)
+	}
+	if len(app.replicas) > 1 {
+		return fmt.Errorf("the service %s has %d replicas, so it does not have a single exit code", app.name, len(app.replicas))
+	}
+	dcService := u.cfg.CanonicalComposeFile.Services[app.name]
+	if dcService.Kind == config.KindDeployment || dcService.Kind == config.KindStatefulSet {
+		return fmt.Errorf("the service %s is a %s, whose containers are restarted when they exit, so it does not have an exit code", app.name,
+			dcService.Kind)
+	}
+	if dcService.Restart == config.RestartAlways || dcService.Restart == config.RestartUnlessStopped {
+		return fmt.Errorf("the service %s has restart policy %s, so its container is restarted when it exits and it does not have an exit code",
+			app.name, dcService.Restart)
+	}
+	u.exitCodeFromApp = app
+	return nil
+}
+
+// isExpectedToComplete returns true if the container of app is supposed to exit, because app is a Job or another app depends on it with
+// condition service_completed_successfully.
+func (u *upRunner) isExpectedToComplete(app *app) bool {
+	if u.cfg.CanonicalComposeFile.Services[app.name].Kind == config.KindJob {
+		return true
+	}
+	for _, dcService := range u.cfg.CanonicalComposeFile.Services {
+		for dependency, healthiness := range dcService.DependsOn {
+			if dependency.ServiceName == app.name && healthiness == config.ServiceCompletedSuccessfully {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// updateAppTerminated records that the container of the pod of a replica of app exited. The run is aborted if the container exited with
+// a non-zero code, if it is the container of the service of ExitCodeFrom, or if app is not expected to complete.
+func (u *upRunner) updateAppTerminated(app *app, replica *replica, pod *v1.Pod, terminated *v1.ContainerStateTerminated) {
+	if replica.terminated {
+		return
+	}
+	replica.terminated = true
+	if terminated.ExitCode == 0 && u.isExpectedToComplete(app) {
+		replica.maxObservedPodStatus = k8sUtil.PodStatusCompleted
+	} else if replica.maxObservedPodStatus < k8sUtil.PodStatusStarted {
+		// The container ran, so dependency conditions of the form service_started are met.
+		replica.maxObservedPodStatus = k8sUtil.PodStatusStarted
+	}
+	u.updateAppFromReplicas(app)
+	fmt.Printf("app %s: container of pod %s exited with code %d\n", app.name, pod.ObjectMeta.Name, terminated.ExitCode)
+	if u.exitApp == nil && (app == u.exitCodeFromApp || terminated.ExitCode != 0 || !u.isExpectedToComplete(app)) {
+		u.exitApp = app
+		u.exitCode = int(terminated.ExitCode)
+	}
+}
+
+// startExitCodeFromLogs begins streaming the logs of the pod of the service of ExitCodeFrom once its container has started.
+func (u *upRunner) startExitCodeFromLogs(app *app, pod *v1.Pod) {
+	if app != u.exitCodeFromApp || u.exitCodeFromLogs != nil {
+		return
+	}
+	if app.maxObservedPodStatus < k8sUtil.PodStatusStarted {
+		return
+	}
+	u.exitCodeFromLogs = make(chan error, 1)
+	u.exitCodeFromLogsStop = make(chan struct{})
+	name := pod.ObjectMeta.Name
+	go func() {
+		u.exitCodeFromLogs <- u.streamPodLogs(name, u.exitCodeFromLogsStop)
+	}()
+}
+
+// isDoneWaitingForExit returns true if a container of ExitCodeFrom exited, a container aborted the run (see updateAppTerminated), or
+// (without ExitCodeFrom) all pods completed. The exit code of up is u.exitCode.
+func (u *upRunner) isDoneWaitingForExit() bool {
+	if u.exitApp != nil {
+		if u.exitApp != u.exitCodeFromApp {
+			fmt.Printf("aborting because the container of app %s exited with code %d\n", u.exitApp.name, u.exitCode)
+		}
+		return true
+	}
+	if u.exitCodeFromApp != nil || len(u.appsWithoutPods) > 0 {
+		return false
+	}
+	for app := range u.appsThatNeedToBeReady {
+		if !app.terminated {
+			return false
+		}
+	}
+	fmt.Printf("all containers exited with code 0\n")
+	return true
+}
+
+// waitForExitCodeFromLogs waits until the logs of ExitCodeFrom are streamed completely. If the run was aborted by another container the
+// stream is closed instead.
+func (u *upRunner) waitForExitCodeFromLogs() error {
+	if u.exitCodeFromLogs == nil {
+		return nil
+	}
+	if u.exitApp != u.exitCodeFromApp {
+		close(u.exitCodeFromLogsStop)
+	}
+	return <-u.exitCodeFromLogs
+}
@@ -0,0 +1,144 @@
+package up
+
+import (
+	"testing"
+
+	k8sUtil "github.com/jbrekelmans/kube-compose/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+)
+
+const testExitComposeFile = `version: '2.4'
+services:
+  tests:
+    image: tests
+    depends_on:
+      migrate:
+        condition: service_completed_successfully
+  migrate:
+    image: migrate
+  job:
+    image: job
+    x-kube-compose:
+      kind: job
+  web:
+    image: web
+  scaled:
+    image: scaled
+    scale: 2
+  deployment:
+    image: deployment
+    x-kube-compose:
+      kind: deployment
+  statefulset:
+    image: statefulset
+    x-kube-compose:
+      kind: statefulset
+  always:
+    image: always
+    restart: always
+  unless-stopped:
+    image: unless-stopped
+    restart: unless-stopped
+`
+
+func TestInitExitCodeFrom(t *testing.T) {
+	testCases := []struct {
+		exitCodeFrom string
+		expectError  bool
+	}{
+		{"tests", false},
+		{"job", false},
+		{"unknown", true},
+		{"scaled", true},
+		{"deployment", true},
+		{"statefulset", true},
+		{"always", true},
+		{"unless-stopped", true},
+	}
+	for _, testCase := range testCases {
+		u := newTestUpRunner(t, testExitComposeFile)
+		u.opts = &Options{ExitCodeFrom: testCase.exitCodeFrom}
+		err := u.initExitCodeFrom()
+		if (err != nil) != testCase.expectError {
+			t.Errorf("%s: unexpected error %v", testCase.exitCodeFrom, err)
+			continue
+		}
+		if !u.opts.AbortOnContainerExit {
+			t.Errorf("%s: expected AbortOnContainerExit to be set", testCase.exitCodeFrom)
+		}
+		if !testCase.expectError && u.exitCodeFromApp != u.apps[testCase.exitCodeFrom] {
+			t.Errorf("%s: unexpected exitCodeFromApp %+v", testCase.exitCodeFrom, u.exitCodeFromApp)
+		}
+	}
+}
+
+func TestIsExpectedToComplete(t *testing.T) {
+	u := newTestUpRunner(t, testExitComposeFile)
+	expected := map[string]bool{
+		"tests":   false,
+		"migrate": true,
+		"job":     true,
+		"web":     false,
+	}
+	for name, expectedToComplete := range expected {
+		if u.isExpectedToComplete(u.apps[name]) != expectedToComplete {
+			t.Errorf("%s: expected isExpectedToComplete to be %t", name, expectedToComplete)
+		}
+	}
+}
+
+func TestUpdateAppTerminated(t *testing.T) {
+	testCases := []struct {
+		name            string
+		exitCodeFrom    string
+		app             string
+		exitCode        int32
+		expectExit      bool
+		expectPodStatus k8sUtil.PodStatus
+	}{
+		{"UnexpectedCleanExit", "", "web", 0, true, k8sUtil.PodStatusStarted},
+		{"DependencyCompleted", "", "migrate", 0, false, k8sUtil.PodStatusCompleted},
+		{"JobCompleted", "", "job", 0, false, k8sUtil.PodStatusCompleted},
+		{"JobFailed", "", "job", 3, true, k8sUtil.PodStatusStarted},
+		{"DependencyFailed", "tests", "migrate", 2, true, k8sUtil.PodStatusStarted},
+		{"ExitCodeFromSucceeded", "tests", "tests", 0, true, k8sUtil.PodStatusStarted},
+		{"ExitCodeFromFailed", "tests", "tests", 1, true, k8sUtil.PodStatusStarted},
+	}
+	for _, testCase := range testCases {
+		u := newTestUpRunner(t, testExitComposeFile)
+		u.opts = &Options{ExitCodeFrom: testCase.exitCodeFrom}
+		if err := u.initExitCodeFrom(); err != nil {
+			t.Fatal(err)
+		}
+		app := u.apps[testCase.app]
+		pod := &v1.Pod{}
+		pod.ObjectMeta.Name = app.nameEncoded + "-" + testEnvironmentID
+		u.updateAppTerminated(app, &app.replicas[0], pod, &v1.ContainerStateTerminated{ExitCode: testCase.exitCode})
+		if !app.terminated || app.maxObservedPodStatus != testCase.expectPodStatus {
+			t.Errorf("%s: unexpected app %+v", testCase.name, app)
+		}
+		if !testCase.expectExit {
+			if u.exitApp != nil || u.isDoneWaitingForExit() {
+				t.Errorf("%s: expected the run not to be aborted, but got exit app %+v", testCase.name, u.exitApp)
+			}
+			continue
+		}
+		if u.exitApp != app || u.exitCode != int(testCase.exitCode) || !u.isDoneWaitingForExit() {
+			t.Errorf("%s: expected exit code %d of app %s, but got exit code %d of %+v", testCase.name, testCase.exitCode, app.name,
+				u.exitCode, u.exitApp)
+		}
+	}
+}
+
+func TestUpdateAppTerminatedKeepsFirstExitCode(t *testing.T) {
+	u := newTestUpRunner(t, testExitComposeFile)
+	u.opts = &Options{AbortOnContainerExit: true}
+	web := u.apps["web"]
+	job := u.apps["job"]
+	pod := &v1.Pod{}
+	u.updateAppTerminated(job, &job.replicas[0], pod, &v1.ContainerStateTerminated{ExitCode: 4})
+	u.updateAppTerminated(web, &web.replicas[0], pod, &v1.ContainerStateTerminated{ExitCode: 0})
+	if u.exitApp != job || u.exitCode != 4 {
+		t.Fatalf("expected exit code 4 of app job, but got exit code %d of %+v", u.exitCode, u.exitApp)
+	}
+}
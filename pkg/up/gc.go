@@ -0,0 +1,46 @@
+package up
+
+import (
+	"time"
+
+	k8sUtil "github.com/jbrekelmans/kube-compose/pkg/k8s"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// initGCAnnotations sets the annotations that kube-compose gc uses to find environments to delete, see Options.TTL and Options.CIJobID.
+func (u *upRunner) initGCAnnotations(ttl time.Duration, ciJobID string) {
+	u.gcAnnotations = map[string]string{
+		k8sUtil.AnnotationCreated: time.Now().UTC().Format(time.RFC3339),
+	}
+	if ttl > 0 {
+		u.gcAnnotations[k8sUtil.AnnotationTTL] = ttl.String()
+	}
+	if len(ciJobID) > 0 {
+		u.gcAnnotations[k8sUtil.AnnotationCIJob] = ciJobID
+	}
+}
+
+// inheritAnchorTTL stamps resources with the TTL of the environment (i.e. of its anchor) if initGCAnnotations was called without a TTL.
+// run uses this, so that one-off commands without a TTL do not keep gc from deleting an environment that expires.
+func (u *upRunner) inheritAnchorTTL() {
+	if _, ok := u.gcAnnotations[k8sUtil.AnnotationTTL]; ok || u.anchor == nil {
+		return
+	}
+	if ttl, ok := u.anchor.ObjectMeta.Annotations[k8sUtil.AnnotationTTL]; ok {
+		u.gcAnnotations[k8sUtil.AnnotationTTL] = ttl
+	}
+}
+
+// stampGCAnnotations adds the annotations of initGCAnnotations to objectMeta. Rendered resources are not stamped, so that they do not
+// depend on the time at which they are rendered.
+func (u *upRunner) stampGCAnnotations(objectMeta *metav1.ObjectMeta) {
+	if len(u.gcAnnotations) == 0 {
+		return
+	}
+	if objectMeta.Annotations == nil {
+		objectMeta.Annotations = map[string]string{}
+	}
+	for key, value := range u.gcAnnotations {
+		objectMeta.Annotations[key] = value
+	}
+}
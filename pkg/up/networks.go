@@ -0,0 +1,96 @@
+package up
+
+import (
+	"fmt"
+	"sort"
+
+	k8sUtil "github.com/jbrekelmans/kube-compose/pkg/k8s"
+	networkingV1 "k8s.io/api/networking/v1"
+	k8sError "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// networkLabel returns the label of pods of apps that are on the docker compose network named name.
+func networkLabel(name string) string {
+	return "kube-compose/network-" + k8sUtil.EncodeName(name)
+}
+
+// newPodNetworkLabels returns the labels of the pod of app that select it into the NetworkPolicies of its networks.
+func (u *upRunner) newPodNetworkLabels(app *app) (map[string]string, error) {
+	labels := map[string]string{}
+	for name := range u.cfg.CanonicalComposeFile.Services[app.name].Networks {
+		label := networkLabel(name)
+		if errors := validation.IsQualifiedName(label); len(errors) > 0 {
+			return nil, fmt.Errorf("app %s: sorry, we do not support the network named %s: %s", app.name, name, errors[0])
+		}
+		labels[label] = "true"
+	}
+	return labels, nil
+}
+
+// getNetworkNames returns the sorted names of the docker compose networks that have at least one app (of serviceApps).
+func (u *upRunner) getNetworkNames() []string {
+	networkNames := []string{}
+	for _, app := range u.apps {
+		if !u.isServiceApp(app) {
+			continue
+		}
+		for name := range u.cfg.CanonicalComposeFile.Services[app.name].Networks {
+			if !contains(networkNames, name) {
+				networkNames = append(networkNames, name)
+			}
+		}
+	}
+	sort.Strings(networkNames)
+	return networkNames
+}
+
+// newNetworkPolicy returns a NetworkPolicy that only allows ingress to pods on the network named name from pods on the same network.
+// Pods on multiple networks are selected by multiple NetworkPolicies, and accept the union of their ingress.
+func (u *upRunner) newNetworkPolicy(name string) *networkingV1.NetworkPolicy {
+	selector := metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			u.cfg.EnvironmentLabel: u.cfg.EnvironmentID,
+			networkLabel(name):     "true",
+		},
+	}
+	networkPolicy := &networkingV1.NetworkPolicy{
+		Spec: networkingV1.NetworkPolicySpec{
+			Ingress: []networkingV1.NetworkPolicyIngressRule{
+				{
+					From: []networkingV1.NetworkPolicyPeer{
+						{
+							PodSelector: &selector,
+						},
+					},
+				},
+			},
+			PodSelector: selector,
+			PolicyTypes: []networkingV1.PolicyType{
+				networkingV1.PolicyTypeIngress,
+			},
+		},
+	}
+	networkPolicy.ObjectMeta.Name = "network-" + k8sUtil.EncodeName(name) + "-" + u.cfg.EnvironmentID
+	networkPolicy.ObjectMeta.Labels = map[string]string{
+		u.cfg.EnvironmentLabel: u.cfg.EnvironmentID,
+	}
+	u.stampObjectMeta(&networkPolicy.ObjectMeta)
+	return networkPolicy
+}
+
+func (u *upRunner) createNetworkPolicies() error {
+	for _, name := range u.getNetworkNames() {
+		networkPolicy := u.newNetworkPolicy(name)
+		_, err := u.k8sNetworkPolicyClient.Create(u.ctx, networkPolicy, metav1.CreateOptions{})
+		if k8sError.IsAlreadyExists(err) {
+			fmt.Printf("network %s: network policy %s already exists\n", name, networkPolicy.ObjectMeta.Name)
+		} else if err != nil {
+			return err
+		} else {
+			fmt.Printf("network %s: created network policy %s\n", name, networkPolicy.ObjectMeta.Name)
+		}
+	}
+	return nil
+}
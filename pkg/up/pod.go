@@ -0,0 +1,64 @@
+package up
+
+import (
+	"github.com/jbrekelmans/kube-compose/pkg/config"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func newResourceList(quantities map[string]string) (v1.ResourceList, error) {
+	if len(quantities) == 0 {
+		return nil, nil
+	}
+	resourceList := v1.ResourceList{}
+	for name, quantityString := range quantities {
+		quantity, err := resource.ParseQuantity(quantityString)
+		if err != nil {
+			return nil, err
+		}
+		resourceList[v1.ResourceName(name)] = quantity
+	}
+	return resourceList, nil
+}
+
+// initPodConfig applies the x-kube-compose of the service of a pod (see config.PodConfig). The labels and annotations of podConfig are
+// added to those of the pod, so that those set by kube-compose are kept.
+func initPodConfig(pod *v1.Pod, podConfig *config.PodConfig) error {
+	for key, value := range podConfig.Labels {
+		if pod.ObjectMeta.Labels == nil {
+			pod.ObjectMeta.Labels = map[string]string{}
+		}
+		pod.ObjectMeta.Labels[key] = value
+	}
+	for key, value := range podConfig.Annotations {
+		if pod.ObjectMeta.Annotations == nil {
+			pod.ObjectMeta.Annotations = map[string]string{}
+		}
+		pod.ObjectMeta.Annotations[key] = value
+	}
+	for _, name := range podConfig.ImagePullSecrets {
+		pod.Spec.ImagePullSecrets = append(pod.Spec.ImagePullSecrets, v1.LocalObjectReference{
+			Name: name,
+		})
+	}
+	pod.Spec.NodeSelector = podConfig.NodeSelector
+	pod.Spec.PriorityClassName = podConfig.PriorityClassName
+	pod.Spec.ServiceAccountName = podConfig.ServiceAccountName
+	for _, toleration := range podConfig.Tolerations {
+		pod.Spec.Tolerations = append(pod.Spec.Tolerations, v1.Toleration{
+			Effect:            v1.TaintEffect(toleration.Effect),
+			Key:               toleration.Key,
+			Operator:          v1.TolerationOperator(toleration.Operator),
+			TolerationSeconds: toleration.TolerationSeconds,
+			Value:             toleration.Value,
+		})
+	}
+	var err error
+	container := &pod.Spec.Containers[0]
+	container.Resources.Limits, err = newResourceList(podConfig.Resources.Limits)
+	if err != nil {
+		return err
+	}
+	container.Resources.Requests, err = newResourceList(podConfig.Resources.Requests)
+	return err
+}
@@ -0,0 +1,225 @@
+package up
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jbrekelmans/kube-compose/pkg/config"
+	v1 "k8s.io/api/core/v1"
+	networkingV1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	RenderFormatJSON = "json"
+	RenderFormatYAML = "yaml"
+	// Cluster IPs are assigned when services are created, so rendered host aliases use this placeholder instead.
+	placeholderClusterIP = "0.0.0.0"
+)
+
+// RenderOptions are the options of a dry run.
+type RenderOptions struct {
+	OutputDir    string // if not empty, one file is written to this directory per resource instead of printing to stdout.
+	OutputFormat string // one of RenderFormatJSON and RenderFormatYAML.
+}
+
+type renderedObject struct {
+	kind   string
+	name   string
+	object runtime.Object
+}
+
+// getAppImageRender returns the image of the pod of app, without pulling, building or pushing images. Because the image is not
+// inspected, images pushed to the registry configured by x-kube-compose.push_images do not have a digest.
+func (u *upRunner) getAppImageRender(app *app) (string, error) {
+	dcService := u.cfg.CanonicalComposeFile.Services[app.name]
+	if u.cfg.PushImages != nil {
+		return fmt.Sprintf("%s/%s/%s:latest", u.cfg.PushImages.DockerRegistry, u.cfg.Namespace, app.nameEncoded), nil
+	}
+	if dcService.Build != nil {
+		return "", fmt.Errorf("docker compose service %s has a build context, but images can only be built if pushing of images is enabled", app.name)
+	}
+	if len(dcService.Image) == 0 {
+		return "", fmt.Errorf("docker compose service %s has no image or image is the empty string, and has no build context", app.name)
+	}
+	return dcService.Image, nil
+}
+
+func (u *upRunner) renderObjects() ([]*renderedObject, error) {
+	if len(u.cfg.Namespace) == 0 {
+		// Pushed images and the DNS search domains of pods contain the namespace.
+		return nil, fmt.Errorf("the namespace is required to render resources, but it is not set by the kubeconfig or --namespace")
+	}
+	objects := []*renderedObject{}
+	add := func(groupVersion schema.GroupVersion, kind string, objectMeta *metav1.ObjectMeta, object runtime.Object) {
+		object.GetObjectKind().SetGroupVersionKind(groupVersion.WithKind(kind))
+		objects = append(objects, &renderedObject{
+			kind:   kind,
+			name:   objectMeta.Name,
+			object: object,
+		})
+	}
+	anchor := u.newAnchor()
+	add(v1.SchemeGroupVersion, "ConfigMap", &anchor.ObjectMeta, anchor)
+	volumeNames := []string{}
+	for name, volume := range u.cfg.CanonicalComposeFile.Volumes {
+		if volume.PersistentVolumeClaim != nil {
+			volumeNames = append(volumeNames, name)
+		}
+	}
+	sort.Strings(volumeNames)
+	for _, name := range volumeNames {
+		pvc, err := u.newPersistentVolumeClaim(u.cfg.CanonicalComposeFile.Volumes[name])
+		if err != nil {
+			return nil, err
+		}
+		add(v1.SchemeGroupVersion, "PersistentVolumeClaim", &pvc.ObjectMeta, pvc)
+	}
+	if u.cfg.NetworkPolicies {
+		for _, name := range u.getNetworkNames() {
+			networkPolicy := u.newNetworkPolicy(name)
+			add(networkingV1.SchemeGroupVersion, "NetworkPolicy", &networkPolicy.ObjectMeta, networkPolicy)
+		}
+	}
+	apps := make([]*app, 0, len(u.apps))
+	for _, app := range u.apps {
+		apps = append(apps, app)
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		return apps[i].name < apps[j].name
+	})
+	if u.needsHeadlessService() {
+		service := u.newHeadlessService()
+		add(v1.SchemeGroupVersion, "Service", &service.ObjectMeta, service)
+	}
+	if u.cfg.DNS.Mode != config.DNSModeSubdomain {
+		for _, app := range apps {
+			if app.hasService {
+				service := u.newService(app)
+				add(v1.SchemeGroupVersion, "Service", &service.ObjectMeta, service)
+				app.serviceClusterIP = placeholderClusterIP
+			}
+		}
+	}
+	for _, app := range apps {
+		if !u.appsWithoutPods[app] {
+			continue
+		}
+		podImage, err := u.getAppImageRender(app)
+		if err != nil {
+			return nil, err
+		}
+		pod, configMaps, err := u.newPod(app, nil, podImage)
+		if err != nil {
+			return nil, err
+		}
+		for _, configMap := range configMaps {
+			add(v1.SchemeGroupVersion, "ConfigMap", &configMap.ObjectMeta, configMap)
+		}
+		if w := u.newWorkload(app, pod); w != nil {
+			add(w.groupVersion, w.kind, w.objectMeta, w.object)
+			continue
+		}
+		for i := range app.replicas {
+			replicaPod := pod.DeepCopy()
+			u.initReplicaObjectMeta(&replicaPod.ObjectMeta, app, i)
+			add(v1.SchemeGroupVersion, "Pod", &replicaPod.ObjectMeta, replicaPod)
+		}
+	}
+	return objects, nil
+}
+
+func marshalRenderedObject(object runtime.Object, format string) ([]byte, error) {
+	if format == RenderFormatJSON {
+		return json.MarshalIndent(object, "", "  ")
+	}
+	return yaml.Marshal(object)
+}
+
+func (u *upRunner) render(opts *RenderOptions) error {
+	err := u.initApps()
+	if err != nil {
+		return err
+	}
+	err = u.checkReplicas()
+	if err != nil {
+		return err
+	}
+	objects, err := u.renderObjects()
+	if err != nil {
+		return err
+	}
+	if len(opts.OutputDir) > 0 {
+		err = os.MkdirAll(opts.OutputDir, 0755)
+		if err != nil {
+			return err
+		}
+		for _, object := range objects {
+			data, err := marshalRenderedObject(object.object, opts.OutputFormat)
+			if err != nil {
+				return err
+			}
+			fileName := filepath.Join(opts.OutputDir, fmt.Sprintf("%s-%s.%s", object.name, strings.ToLower(object.kind), opts.OutputFormat))
+			err = ioutil.WriteFile(fileName, data, 0644)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("wrote %s %s to %s\n", object.kind, object.name, fileName)
+		}
+		return nil
+	}
+	if opts.OutputFormat == RenderFormatJSON {
+		list := &v1.List{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "v1",
+				Kind:       "List",
+			},
+			Items: make([]runtime.RawExtension, len(objects)),
+		}
+		for i, object := range objects {
+			list.Items[i].Raw, err = json.Marshal(object.object)
+			if err != nil {
+				return err
+			}
+		}
+		data, err := json.MarshalIndent(list, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	for i, object := range objects {
+		data, err := yaml.Marshal(object.object)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Println("---")
+		}
+		fmt.Print(string(data))
+	}
+	return nil
+}
+
+// Render builds the Kubernetes resources that Run would create, and prints them or writes them to files, without contacting the
+// Kubernetes cluster or the docker daemon.
+func Render(cfg *config.Config, opts *RenderOptions) error {
+	switch opts.OutputFormat {
+	case RenderFormatJSON, RenderFormatYAML:
+	default:
+		return fmt.Errorf("unsupported output format %#v, must be one of %s and %s", opts.OutputFormat, RenderFormatJSON, RenderFormatYAML)
+	}
+	u := &upRunner{
+		cfg: cfg,
+	}
+	return u.render(opts)
+}
@@ -0,0 +1,105 @@
+package up
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+const testRenderComposeFile = `version: '2.4'
+services:
+  db:
+    image: postgres:11
+    ports:
+    - 5432:5432
+  web:
+    image: nginx
+    depends_on:
+      db:
+        condition: service_started
+  migrate:
+    image: migrate
+    depends_on:
+      db:
+        condition: service_started
+`
+
+func TestRenderObjects(t *testing.T) {
+	u := newTestUpRunner(t, testRenderComposeFile)
+	objects, err := u.renderObjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		kind string
+		name string
+	}{
+		{"ConfigMap", "kube-compose-" + testEnvironmentID},
+		{"Service", "db-" + testEnvironmentID},
+		{"Pod", "db-" + testEnvironmentID},
+		{"Pod", "migrate-" + testEnvironmentID},
+		{"Pod", "web-" + testEnvironmentID},
+	}
+	if len(objects) != len(expected) {
+		for _, object := range objects {
+			t.Logf("%s %s", object.kind, object.name)
+		}
+		t.Fatalf("expected %d objects, but got %d", len(expected), len(objects))
+	}
+	for i, object := range objects {
+		if object.kind != expected[i].kind || object.name != expected[i].name {
+			t.Errorf("object %d: expected %s %s, but got %s %s", i+1, expected[i].kind, expected[i].name, object.kind, object.name)
+		}
+		if object.object.GetObjectKind().GroupVersionKind().Kind != object.kind {
+			t.Errorf("object %d: kind of %s %s is not set", i+1, object.kind, object.name)
+		}
+	}
+	pod := objects[4].object.(*v1.Pod)
+	if pod.Spec.Containers[0].Image != "nginx" {
+		t.Errorf("expected image nginx, but got %s", pod.Spec.Containers[0].Image)
+	}
+	found := false
+	for _, hostAlias := range pod.Spec.HostAliases {
+		if hostAlias.IP == placeholderClusterIP {
+			for _, hostname := range hostAlias.Hostnames {
+				found = found || hostname == "db"
+			}
+		}
+	}
+	if !found {
+		t.Errorf("expected host alias of db with placeholder cluster IP, but got %+v", pod.Spec.HostAliases)
+	}
+}
+
+func TestGetAppImageRender(t *testing.T) {
+	u := newTestUpRunner(t, "version: '2.4'\nservices:\n  web:\n    build:\n      context: .\n")
+	_, err := u.renderObjects()
+	if err == nil {
+		t.Error("expected error because images are not built without push_images")
+	}
+	u = newTestUpRunner(t, `version: '2.4'
+services:
+  web:
+    build:
+      context: .
+x-kube-compose:
+  push_images:
+    docker_registry: reg.example.com
+`)
+	image, err := u.getAppImageRender(u.apps["web"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image != "reg.example.com/default/web:latest" {
+		t.Errorf("unexpected image %s", image)
+	}
+}
+
+func TestRenderObjectsWithoutNamespace(t *testing.T) {
+	u := newTestUpRunner(t, testRenderComposeFile)
+	u.cfg.Namespace = ""
+	_, err := u.renderObjects()
+	if err == nil {
+		t.Error("expected an error, because image names and DNS search domains cannot be rendered without a namespace")
+	}
+}
@@ -0,0 +1,54 @@
+package up
+
+import (
+	"fmt"
+
+	"github.com/jbrekelmans/kube-compose/pkg/config"
+	k8sUtil "github.com/jbrekelmans/kube-compose/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+)
+
+// newRestartPolicy returns the restart policy of the pods of a service, see config.Service.Restart.
+func newRestartPolicy(dcService *config.Service) v1.RestartPolicy {
+	switch dcService.Restart {
+	case config.RestartAlways, config.RestartUnlessStopped:
+		return v1.RestartPolicyAlways
+	case config.RestartOnFailure:
+		return v1.RestartPolicyOnFailure
+	}
+	return v1.RestartPolicyNever
+}
+
+// checkRestarts aborts the run if a container of pod terminated again after it was restarted the maximum number of times, so that
+// containers in a crash loop fail the run. The maximum is opts.MaxRestarts, unless overridden by restart: on-failure:max-retries.
+func (u *upRunner) checkRestarts(app *app, pod *v1.Pod) error {
+	maxRestarts := u.opts.MaxRestarts
+	if maxRetries := u.cfg.CanonicalComposeFile.Services[app.name].RestartMaxRetries; maxRetries > 0 {
+		maxRestarts = maxRetries
+	}
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		if int(containerStatus.RestartCount) < maxRestarts || containerStatus.State.Running != nil {
+			continue
+		}
+		// Containers waiting to be restarted (e.g. in CrashLoopBackOff) have their last termination state.
+		t := containerStatus.State.Terminated
+		if t == nil {
+			t = containerStatus.LastTerminationState.Terminated
+		} else if !k8sUtil.WillRestart(pod, t) {
+			continue
+		}
+		if t == nil {
+			continue
+		}
+		return fmt.Errorf("aborting because container %s of pod %s terminated after %d restarts (code=%d,signal=%d,reason=%s): %s",
+			containerStatus.Name,
+			pod.ObjectMeta.Name,
+			containerStatus.RestartCount,
+			t.ExitCode,
+			t.Signal,
+			t.Reason,
+			t.Message,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package up
+
+import (
+	"testing"
+
+	"github.com/jbrekelmans/kube-compose/pkg/config"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestNewRestartPolicy(t *testing.T) {
+	expected := map[string]v1.RestartPolicy{
+		"":                          v1.RestartPolicyNever,
+		config.RestartNo:            v1.RestartPolicyNever,
+		config.RestartAlways:        v1.RestartPolicyAlways,
+		config.RestartUnlessStopped: v1.RestartPolicyAlways,
+		config.RestartOnFailure:     v1.RestartPolicyOnFailure,
+	}
+	for restart, restartPolicy := range expected {
+		actual := newRestartPolicy(&config.Service{Restart: restart})
+		if actual != restartPolicy {
+			t.Errorf("restart %#v: expected %s, but got %s", restart, restartPolicy, actual)
+		}
+	}
+}
+
+// newTestRestartedPod returns a pod with a single container that was restarted restartCount times and is now in state.
+func newTestRestartedPod(restartPolicy v1.RestartPolicy, restartCount int32, state v1.ContainerState) *v1.Pod {
+	containerStatus := v1.ContainerStatus{
+		Name:         "web",
+		RestartCount: restartCount,
+		State:        state,
+	}
+	if state.Waiting != nil {
+		containerStatus.LastTerminationState.Terminated = &v1.ContainerStateTerminated{ExitCode: 1}
+	}
+	pod := &v1.Pod{}
+	pod.ObjectMeta.Name = "web-test123"
+	pod.Spec.RestartPolicy = restartPolicy
+	pod.Status.ContainerStatuses = []v1.ContainerStatus{containerStatus}
+	return pod
+}
+
+func TestCheckRestarts(t *testing.T) {
+	u := newTestUpRunner(t, `version: '2.4'
+services:
+  web:
+    image: web
+    restart: always
+  worker:
+    image: worker
+    restart: on-failure:5
+`)
+	u.opts = &Options{MaxRestarts: 3}
+	running := v1.ContainerState{Running: &v1.ContainerStateRunning{}}
+	crashLoop := v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}
+	failed := v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1}}
+	completed := v1.ContainerState{Terminated: &v1.ContainerStateTerminated{}}
+	testCases := []struct {
+		name        string
+		app         string
+		pod         *v1.Pod
+		expectError bool
+	}{
+		{"RunningAfterRestarts", "web", newTestRestartedPod(v1.RestartPolicyAlways, 5, running), false},
+		{"CrashLoopBelowMaxRestarts", "web", newTestRestartedPod(v1.RestartPolicyAlways, 2, crashLoop), false},
+		{"CrashLoopAtMaxRestarts", "web", newTestRestartedPod(v1.RestartPolicyAlways, 3, crashLoop), true},
+		{"FailedAtMaxRestarts", "web", newTestRestartedPod(v1.RestartPolicyAlways, 3, failed), true},
+		{"CompletedOnFailure", "worker", newTestRestartedPod(v1.RestartPolicyOnFailure, 3, completed), false},
+		{"CrashLoopBelowMaxRetries", "worker", newTestRestartedPod(v1.RestartPolicyOnFailure, 4, crashLoop), false},
+		{"CrashLoopAtMaxRetries", "worker", newTestRestartedPod(v1.RestartPolicyOnFailure, 5, crashLoop), true},
+	}
+	for _, testCase := range testCases {
+		err := u.checkRestarts(u.apps[testCase.app], testCase.pod)
+		if (err != nil) != testCase.expectError {
+			t.Errorf("%s: unexpected error %v", testCase.name, err)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package up
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	dockerClient "github.com/docker/docker/client"
+	"github.com/jbrekelmans/kube-compose/pkg/config"
+	k8sUtil "github.com/jbrekelmans/kube-compose/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/rand"
+)
+
+// annotationRun is the annotation of one-off pods that holds the name of the docker compose service they were created for. One-off pods
+// do not have the annotation kube-compose/service, so that they are not mistaken for the pod of the service.
+const annotationRun = "kube-compose/run"
+
+// RunOptions are the options of a one-off command.
+type RunOptions struct {
+	CIJobID string   // see Options.CIJobID.
+	Command []string // overrides the command of the service if not empty.
+	Keep    bool     // whether to keep the pod after it terminated.
+	Service string
+	TTL     time.Duration // see Options.TTL.
+}
+
+// waitForOneOffPod waits until done returns true for the pod named name.
+func (u *upRunner) waitForOneOffPod(name string, done func(*v1.Pod) (bool, error)) (*v1.Pod, error) {
+	listOptions := metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", name).String(),
+	}
+	podList, err := u.k8sPodClient.List(u.ctx, listOptions)
+	if err != nil {
+		return nil, err
+	}
+	if len(podList.Items) == 0 {
+		return nil, errorResourcesModifiedExternally()
+	}
+	pod := &podList.Items[0]
+	ok, err := done(pod)
+	if err != nil || ok {
+		return pod, err
+	}
+	list := func(ctx context.Context, listOptions metav1.ListOptions) (runtime.Object, error) {
+		return u.k8sPodClient.List(ctx, listOptions)
+	}
+	w, err := newWatcher(u.ctx, listOptions, podList, list, u.k8sPodClient.Watch)
+	if err != nil {
+		return nil, err
+	}
+	defer w.stop()
+	for {
+		event, err := w.next()
+		if err != nil {
+			return nil, err
+		}
+		if event.Type == "ADDED" || event.Type == "MODIFIED" {
+			pod = event.Object.(*v1.Pod)
+			ok, err = done(pod)
+			if err != nil || ok {
+				return pod, err
+			}
+		} else if event.Type == "DELETED" {
+			return nil, errorResourcesModifiedExternally()
+		} else {
+			return nil, fmt.Errorf("got unexpected error event from channel: %+v", event.Object)
+		}
+	}
+}
+
+func isOneOffPodStarted(pod *v1.Pod) (bool, error) {
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		if containerStatus.State.Running != nil || containerStatus.State.Terminated != nil {
+			return true, nil
+		}
+		if w := containerStatus.State.Waiting; w != nil && (w.Reason == "ErrImagePull" || w.Reason == "ImagePullBackOff") {
+			return false, fmt.Errorf("container %s of pod %s could not pull image: %s", containerStatus.Name, pod.ObjectMeta.Name, w.Message)
+		}
+	}
+	return false, nil
+}
+
+func isOneOffPodTerminated(pod *v1.Pod) (bool, error) {
+	return k8sUtil.GetTerminatedContainerState(pod) != nil, nil
+}
+
+// streamPodLogs copies the logs of the pod named name to stdout until its container terminated, ctx is done or stop is closed.
+func (u *upRunner) streamPodLogs(name string, stop <-chan struct{}) error {
+	stream, err := u.k8sPodClient.GetLogs(name, &v1.PodLogOptions{
+		Follow: true,
+	}).Stream(u.ctx)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	// Closing the stream aborts the copy when ctx is done or stop is closed.
+	copyDone := make(chan struct{})
+	defer close(copyDone)
+	go func() {
+		select {
+		case <-u.ctx.Done():
+			stream.Close()
+		case <-stop:
+			stream.Close()
+		case <-copyDone:
+		}
+	}()
+	_, err = io.Copy(os.Stdout, stream)
+	if u.ctx.Err() != nil {
+		return u.ctx.Err()
+	}
+	select {
+	case <-stop:
+		return nil
+	default:
+	}
+	return err
+}
+
+// getDependencySet returns app1 and the apps it depends on (transitively).
+func (u *upRunner) getDependencySet(app1 *app) map[*app]bool {
+	apps := map[*app]bool{}
+	queue := []*app{app1}
+	for len(queue) > 0 {
+		app2 := queue[0]
+		queue = queue[1:]
+		if apps[app2] {
+			continue
+		}
+		apps[app2] = true
+		for dcService := range u.cfg.CanonicalComposeFile.Services[app2.name].DependsOn {
+			queue = append(queue, u.apps[dcService.ServiceName])
+		}
+	}
+	return apps
+}
+
+func (u *upRunner) runOneOff(opts *RunOptions) (int, error) {
+	err := u.initApps()
+	if err != nil {
+		return 0, err
+	}
+	app := u.apps[opts.Service]
+	if app == nil {
+		return 0, fmt.Errorf("no service named %#v exists", opts.Service)
+	}
+	// Like docker-compose run, only the services of app and its dependencies are created. Other apps are resolvable if their services
+	// already exist (e.g. because up was run before).
+	u.serviceApps = u.getDependencySet(app)
+	err = u.initKubernetesClientset()
+	if err != nil {
+		return 0, err
+	}
+	err = u.createAnchor()
+	if err != nil {
+		return 0, err
+	}
+	u.inheritAnchorTTL()
+	dockerClient, err := dockerClient.NewEnvClient()
+	if err != nil {
+		return 0, err
+	}
+	u.dockerClient = dockerClient
+	err = u.createPersistentVolumeClaims()
+	if err != nil {
+		return 0, err
+	}
+	pod, configMaps, err := u.newPodWithDependencies(app)
+	if err != nil {
+		return 0, err
+	}
+	err = u.createConfigMaps(app, configMaps)
+	if err != nil {
+		return 0, err
+	}
+	u.initOneOffPod(app, pod, opts.Command)
+	return u.runOneOffPod(app, pod, opts.Keep)
+}
+
+// initOneOffPod turns pod (the pod of app, see newPod) into a pod that runs command (or the command of the service if command is empty).
+func (u *upRunner) initOneOffPod(app *app, pod *v1.Pod, command []string) {
+	container := &pod.Spec.Containers[0]
+	if len(command) > 0 {
+		container.Args = command
+	}
+	// The readiness probe is only used to implement depends_on, and the healthcheck of the service need not apply to the command.
+	container.LivenessProbe = nil
+	container.ReadinessProbe = nil
+	container.StartupProbe = nil
+	// Like docker-compose run, the restart policy of the service does not apply to one-off commands.
+	pod.Spec.RestartPolicy = v1.RestartPolicyNever
+	// The name of the app should keep resolving to the pod of the app.
+	pod.Spec.Hostname = ""
+	pod.Spec.Subdomain = ""
+	// One-off pods do not have the label app, so that they are not selected by the service of app. They keep the labels of their
+	// networks and of x-kube-compose.
+	pod.ObjectMeta.Name = fmt.Sprintf("%s-run-%s-%s", app.nameEncoded, rand.String(5), u.cfg.EnvironmentID)
+	pod.ObjectMeta.Labels[u.cfg.EnvironmentLabel] = u.cfg.EnvironmentID
+	if pod.ObjectMeta.Annotations == nil {
+		pod.ObjectMeta.Annotations = map[string]string{}
+	}
+	pod.ObjectMeta.Annotations[annotationRun] = app.name
+	u.stampObjectMeta(&pod.ObjectMeta)
+}
+
+// runOneOffPod creates the one-off pod of app, streams its logs and returns the exit code of its container. The pod is deleted when its
+// container terminated, unless keep is true.
+func (u *upRunner) runOneOffPod(app *app, pod *v1.Pod, keep bool) (int, error) {
+	_, err := u.k8sPodClient.Create(u.ctx, pod, metav1.CreateOptions{})
+	if err != nil {
+		return 0, err
+	}
+	fmt.Printf("app %s: created pod %s\n", app.name, pod.ObjectMeta.Name)
+	if !keep {
+		defer func() {
+			// The pod is also deleted if ctx was cancelled (e.g. by an interrupt).
+			err := u.k8sPodClient.Delete(context.Background(), pod.ObjectMeta.Name, metav1.DeleteOptions{})
+			if err != nil {
+				fmt.Printf("app %s: error while deleting pod %s: %v\n", app.name, pod.ObjectMeta.Name, err)
+			} else {
+				fmt.Printf("app %s: deleted pod %s\n", app.name, pod.ObjectMeta.Name)
+			}
+		}()
+	}
+	_, err = u.waitForOneOffPod(pod.ObjectMeta.Name, isOneOffPodStarted)
+	if err != nil {
+		return 0, err
+	}
+	err = u.streamPodLogs(pod.ObjectMeta.Name, nil)
+	if err != nil {
+		return 0, err
+	}
+	podServer, err := u.waitForOneOffPod(pod.ObjectMeta.Name, isOneOffPodTerminated)
+	if err != nil {
+		return 0, err
+	}
+	return int(k8sUtil.GetTerminatedContainerState(podServer).ExitCode), nil
+}
+
+// RunOneOff runs an operation similar to docker-compose run against a Kubernetes cluster. It creates a pod with the same spec as the
+// pod of the service, streams its output and returns the exit code of its container.
+func RunOneOff(ctx context.Context, cfg *config.Config, opts *RunOptions) (int, error) {
+	u := &upRunner{
+		cfg:                  cfg,
+		ctx:                  ctx,
+		localImagesCacheOnce: &sync.Once{},
+		servicesOnce:         &sync.Once{},
+	}
+	u.initGCAnnotations(opts.TTL, opts.CIJobID)
+	return u.runOneOff(opts)
+}
@@ -0,0 +1,133 @@
+package up
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	k8sUtil "github.com/jbrekelmans/kube-compose/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+	k8sError "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testRunComposeFile = `version: '2.4'
+services:
+  web:
+    image: nginx
+    command: ["nginx", "-g", "daemon off;"]
+    restart: always
+    healthcheck:
+      test: ["CMD", "curl", "-f", "http://localhost"]
+x-kube-compose:
+  dns:
+    mode: subdomain
+`
+
+func TestInitOneOffPod(t *testing.T) {
+	u := newTestUpRunner(t, testRunComposeFile)
+	u.anchor = &v1.ConfigMap{}
+	u.anchor.ObjectMeta.Name = k8sUtil.AnchorName(testEnvironmentID)
+	u.anchor.ObjectMeta.UID = types.UID("1234")
+	u.initGCAnnotations(0, "")
+	app := u.apps["web"]
+	pod, _, err := u.newPod(app, nil, "nginx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.initOneOffPod(app, pod, []string{"nginx", "-t"})
+
+	if !strings.HasPrefix(pod.ObjectMeta.Name, "web-run-") || !strings.HasSuffix(pod.ObjectMeta.Name, "-"+testEnvironmentID) {
+		t.Errorf("unexpected name %s", pod.ObjectMeta.Name)
+	}
+	if _, ok := pod.ObjectMeta.Labels["app"]; ok {
+		t.Error("expected the one-off pod not to be selected by the service of web")
+	}
+	if pod.ObjectMeta.Labels[u.cfg.EnvironmentLabel] != testEnvironmentID {
+		t.Errorf("expected the environment label, but got %v", pod.ObjectMeta.Labels)
+	}
+	if pod.ObjectMeta.Annotations[annotationRun] != "web" {
+		t.Errorf("expected annotation %s, but got %v", annotationRun, pod.ObjectMeta.Annotations)
+	}
+	if _, ok := pod.ObjectMeta.Annotations[k8sUtil.AnnotationName]; ok {
+		t.Error("expected the one-off pod not to be mistaken for the pod of web")
+	}
+	if _, ok := pod.ObjectMeta.Annotations[k8sUtil.AnnotationCreated]; !ok {
+		t.Error("expected the one-off pod to be stamped")
+	}
+	if owners := pod.ObjectMeta.OwnerReferences; len(owners) != 1 || owners[0].UID != u.anchor.ObjectMeta.UID {
+		t.Errorf("expected the anchor to be the owner, but got %+v", owners)
+	}
+	container := pod.Spec.Containers[0]
+	if strings.Join(container.Args, " ") != "nginx -t" {
+		t.Errorf("expected the command to be overridden, but got %v", container.Args)
+	}
+	if container.ReadinessProbe != nil || container.LivenessProbe != nil || container.StartupProbe != nil {
+		t.Error("expected the one-off pod to have no probes")
+	}
+	if pod.Spec.RestartPolicy != v1.RestartPolicyNever {
+		t.Errorf("expected restart policy %s, but got %s", v1.RestartPolicyNever, pod.Spec.RestartPolicy)
+	}
+	if len(pod.Spec.Hostname) > 0 || len(pod.Spec.Subdomain) > 0 {
+		t.Errorf("expected the one-off pod not to take over the hostname of web, but got %s.%s", pod.Spec.Hostname, pod.Spec.Subdomain)
+	}
+}
+
+// newTestOneOffPod returns a one-off pod whose container already terminated with exitCode, since fake clientsets do not run pods.
+func newTestOneOffPod(exitCode int32) *v1.Pod {
+	pod := &v1.Pod{}
+	pod.ObjectMeta.Name = "web-run-abcde-" + testEnvironmentID
+	pod.Status.ContainerStatuses = []v1.ContainerStatus{
+		{
+			Name: "web",
+			State: v1.ContainerState{
+				Terminated: &v1.ContainerStateTerminated{ExitCode: exitCode},
+			},
+		},
+	}
+	return pod
+}
+
+func TestRunOneOffPod(t *testing.T) {
+	for _, keep := range []bool{false, true} {
+		u := newTestUpRunner(t, testRunComposeFile)
+		u.k8sPodClient = fake.NewSimpleClientset().CoreV1().Pods(u.cfg.Namespace)
+		pod := newTestOneOffPod(3)
+		exitCode, err := u.runOneOffPod(u.apps["web"], pod, keep)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exitCode != 3 {
+			t.Errorf("keep %t: expected exit code 3, but got %d", keep, exitCode)
+		}
+		_, err = u.k8sPodClient.Get(context.Background(), pod.ObjectMeta.Name, metav1.GetOptions{})
+		if keep && err != nil {
+			t.Errorf("expected the pod to be kept, but got %v", err)
+		} else if !keep && !k8sError.IsNotFound(err) {
+			t.Errorf("expected the pod to be deleted, but got %v", err)
+		}
+	}
+}
+
+func TestInheritAnchorTTL(t *testing.T) {
+	anchor := &v1.ConfigMap{}
+	anchor.ObjectMeta.Annotations = map[string]string{
+		k8sUtil.AnnotationTTL: "2h0m0s",
+	}
+	u := &upRunner{
+		anchor: anchor,
+	}
+	u.initGCAnnotations(0, "")
+	u.inheritAnchorTTL()
+	if ttl := u.gcAnnotations[k8sUtil.AnnotationTTL]; ttl != "2h0m0s" {
+		t.Errorf("expected the TTL of the anchor, but got %#v", ttl)
+	}
+	u.initGCAnnotations(time.Hour, "")
+	u.inheritAnchorTTL()
+	if ttl := u.gcAnnotations[k8sUtil.AnnotationTTL]; ttl != "1h0m0s" {
+		t.Errorf("expected the TTL of run to take precedence, but got %#v", ttl)
+	}
+}
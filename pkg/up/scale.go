@@ -0,0 +1,131 @@
+package up
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/jbrekelmans/kube-compose/pkg/config"
+	k8sUtil "github.com/jbrekelmans/kube-compose/pkg/k8s"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// replica is the observed state of one of the pods of an app.
+type replica struct {
+	maxObservedPodStatus k8sUtil.PodStatus
+	podName              string // the name of the pod, if the pod is managed by the workload of the app.
+	terminated           bool
+}
+
+// checkReplicas rejects apps with multiple replicas if names are resolved via cluster DNS, because the hostname of each pod is the name
+// of its app.
+func (u *upRunner) checkReplicas() error {
+	if u.cfg.DNS.Mode != config.DNSModeSubdomain {
+		return nil
+	}
+	for app := range u.appsWithoutPods {
+		if len(app.replicas) > 1 {
+			return fmt.Errorf("app %s has %d replicas, but x-kube-compose.dns.mode %s does not support multiple replicas", app.name,
+				len(app.replicas), config.DNSModeSubdomain)
+		}
+	}
+	return nil
+}
+
+// initReplicaObjectMeta initializes the object metadata of the pod of the i-th replica of app (see initResourceObjectMeta). Apps with a
+// single replica keep the name of their pod unchanged.
+func (u *upRunner) initReplicaObjectMeta(objectMeta *metav1.ObjectMeta, app *app, i int) {
+	u.initResourceObjectMeta(objectMeta, app.nameEncoded, app.name)
+	if len(app.replicas) == 1 {
+		return
+	}
+	objectMeta.Name = fmt.Sprintf("%s-%d-%s", app.nameEncoded, i+1, u.cfg.EnvironmentID)
+	objectMeta.Annotations[k8sUtil.AnnotationReplica] = strconv.Itoa(i + 1)
+}
+
+// findReplica returns the replica of app that the pod with object metadata objectMeta was created for, see findWorkloadReplica for pods
+// managed by workloads.
+func findReplica(app *app, objectMeta *metav1.ObjectMeta) (*replica, error) {
+	if metav1.GetControllerOf(objectMeta) != nil {
+		return findWorkloadReplica(app, objectMeta.Name), nil
+	}
+	value, ok := objectMeta.Annotations[k8sUtil.AnnotationReplica]
+	if !ok {
+		if len(app.replicas) == 1 {
+			return &app.replicas[0], nil
+		}
+		return nil, errorResourcesModifiedExternally()
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 1 || i > len(app.replicas) {
+		return nil, errorResourcesModifiedExternally()
+	}
+	return &app.replicas[i-1], nil
+}
+
+// minReplicaPodStatus returns the lowest pod status of the replicas of app.
+func (app *app) minReplicaPodStatus() k8sUtil.PodStatus {
+	podStatus := app.replicas[0].maxObservedPodStatus
+	for i := 1; i < len(app.replicas); i++ {
+		if app.replicas[i].maxObservedPodStatus < podStatus {
+			podStatus = app.replicas[i].maxObservedPodStatus
+		}
+	}
+	return podStatus
+}
+
+// updateAppFromReplicas updates the pod status of app after the status of one of its replicas changed. Dependency conditions are met
+// once all replicas meet them, or at least one replica if the service's replicas_ready is one.
+func (u *upRunner) updateAppFromReplicas(app *app) {
+	podStatus := app.minReplicaPodStatus()
+	if u.cfg.CanonicalComposeFile.Services[app.name].ReplicasReady == config.ReplicasReadyOne {
+		for _, replica := range app.replicas {
+			if replica.maxObservedPodStatus > podStatus {
+				podStatus = replica.maxObservedPodStatus
+			}
+		}
+	}
+	app.terminated = true
+	for _, replica := range app.replicas {
+		if !replica.terminated {
+			app.terminated = false
+		}
+	}
+	if podStatus > app.maxObservedPodStatus {
+		app.maxObservedPodStatus = podStatus
+		fmt.Printf("app %s: pod status %s\n", app.name, &app.maxObservedPodStatus)
+	}
+}
+
+// findWorkloadReplica returns the replica of app that tracks the pod named podName, which is managed by the workload of app. Pods of
+// workloads are assigned to the first untracked replica. Returns nil if all replicas are tracked by other pods.
+func findWorkloadReplica(app *app, podName string) *replica {
+	var untracked *replica
+	for i := range app.replicas {
+		if app.replicas[i].podName == podName {
+			return &app.replicas[i]
+		}
+		if untracked == nil && len(app.replicas[i].podName) == 0 {
+			untracked = &app.replicas[i]
+		}
+	}
+	if untracked != nil {
+		untracked.podName = podName
+	}
+	return untracked
+}
+
+// removeWorkloadReplica stops tracking the deleted pod with object metadata objectMeta. Deployments and StatefulSets replace deleted
+// pods, so their replicas are tracked again once the replacement is observed. Deleting any other pod modifies the environment
+// externally.
+func removeWorkloadReplica(app *app, objectMeta *metav1.ObjectMeta) error {
+	owner := metav1.GetControllerOf(objectMeta)
+	if owner == nil || owner.Kind == "Job" {
+		return errorResourcesModifiedExternally()
+	}
+	for i := range app.replicas {
+		if app.replicas[i].podName == objectMeta.Name {
+			app.replicas[i] = replica{}
+		}
+	}
+	return nil
+}
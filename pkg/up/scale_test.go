@@ -0,0 +1,117 @@
+package up
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jbrekelmans/kube-compose/pkg/config"
+	k8sUtil "github.com/jbrekelmans/kube-compose/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testScaleComposeFile = `version: '2.4'
+services:
+  web:
+    image: web
+    scale: 3
+  db:
+    image: db
+    scale: 2
+    x-kube-compose:
+      replicas_ready: one
+  deployment:
+    image: deployment
+    restart: always
+    scale: 2
+    x-kube-compose:
+      kind: deployment
+`
+
+// newTestReadyReplicaPod returns the ready pod of the i-th replica of app.
+func newTestReadyReplicaPod(u *upRunner, app *app, i int) *v1.Pod {
+	pod := &v1.Pod{}
+	u.initReplicaObjectMeta(&pod.ObjectMeta, app, i)
+	pod.Status.Conditions = []v1.PodCondition{
+		{Type: v1.PodReady, Status: v1.ConditionTrue},
+	}
+	return pod
+}
+
+// expectReplicasReady updates the pod status of app with the ready pods of its replicas one at a time, and checks that app is only ready
+// once all its pods are, and that dependency conditions are met once dependencyReadyAfter pods are ready.
+func expectReplicasReady(t *testing.T, u *upRunner, app *app, pods []*v1.Pod, dependencyReadyAfter int) {
+	for i, pod := range pods {
+		err := u.updateAppMaxObservedPodStatus(pod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ready := u.isAppReady(app); ready != (i == len(pods)-1) {
+			t.Errorf("app %s with %d of %d pods ready: unexpected readiness %t", app.name, i+1, len(pods), ready)
+		}
+		dependencyReady := isDependencyConditionMet(app, config.ServiceHealthy)
+		if dependencyReady != (i+1 >= dependencyReadyAfter) {
+			t.Errorf("app %s with %d of %d pods ready: unexpected dependency condition %t", app.name, i+1, len(pods), dependencyReady)
+		}
+	}
+}
+
+func TestReplicasReadyAll(t *testing.T) {
+	u := newTestUpRunner(t, testScaleComposeFile)
+	u.opts = &Options{}
+	app := u.apps["web"]
+	pods := []*v1.Pod{
+		newTestReadyReplicaPod(u, app, 2),
+		newTestReadyReplicaPod(u, app, 0),
+		newTestReadyReplicaPod(u, app, 1),
+	}
+	expectReplicasReady(t, u, app, pods, 3)
+}
+
+func TestReplicasReadyOne(t *testing.T) {
+	u := newTestUpRunner(t, testScaleComposeFile)
+	u.opts = &Options{}
+	app := u.apps["db"]
+	pods := []*v1.Pod{
+		newTestReadyReplicaPod(u, app, 1),
+		newTestReadyReplicaPod(u, app, 0),
+	}
+	expectReplicasReady(t, u, app, pods, 1)
+}
+
+func TestReplicasReadySamePod(t *testing.T) {
+	u := newTestUpRunner(t, testScaleComposeFile)
+	u.opts = &Options{}
+	app := u.apps["web"]
+	pod := newTestReadyReplicaPod(u, app, 0)
+	for i := 0; i < len(app.replicas); i++ {
+		err := u.updateAppMaxObservedPodStatus(pod)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if u.isAppReady(app) || app.maxObservedPodStatus == k8sUtil.PodStatusReady {
+		t.Fatal("expected the app not to be ready while only one of its pods is ready")
+	}
+}
+
+func TestReplicasReadyWorkload(t *testing.T) {
+	u := newTestUpRunner(t, testScaleComposeFile)
+	u.opts = &Options{}
+	app := u.apps["deployment"]
+	isController := true
+	pods := make([]*v1.Pod, len(app.replicas))
+	for i := range pods {
+		pod := &v1.Pod{}
+		u.initResourceObjectMeta(&pod.ObjectMeta, app.nameEncoded, app.name)
+		pod.ObjectMeta.Name = fmt.Sprintf("%s-%d", pod.ObjectMeta.Name, i)
+		pod.ObjectMeta.OwnerReferences = []metav1.OwnerReference{
+			{Kind: "ReplicaSet", Name: app.nameEncoded, Controller: &isController},
+		}
+		pod.Status.Conditions = []v1.PodCondition{
+			{Type: v1.PodReady, Status: v1.ConditionTrue},
+		}
+		pods[i] = pod
+	}
+	expectReplicasReady(t, u, app, pods, len(pods))
+}
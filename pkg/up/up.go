@@ -3,6 +3,7 @@ package up
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 
@@ -15,38 +16,22 @@ import (
 	digest "github.com/opencontainers/go-digest"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"k8s.io/client-go/kubernetes"
+	clientAppsV1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+	clientBatchV1 "k8s.io/client-go/kubernetes/typed/batch/v1"
 	clientV1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	networkingV1 "k8s.io/client-go/kubernetes/typed/networking/v1"
 
 	k8sError "k8s.io/apimachinery/pkg/api/errors"
-
 )
 
 func errorResourcesModifiedExternally() error {
 	return fmt.Errorf("one or more resources appear to have been modified by an external process, aborting")
 }
 
-type podStatus int
-
-const (
-	annotationName             = "kube-compose/service"
-	podStatusReady   podStatus = 2
-	podStatusStarted podStatus = 1
-	podStatusOther   podStatus = 0
-)
-
-func (podStatus *podStatus) String() string {
-	switch *podStatus {
-	case podStatusReady:
-		return "ready"
-	case podStatusStarted:
-		return "started"
-	}
-	return "other"
-}
-
 type appImage struct {
 	imageHealthcheck *config.Healthcheck
 	podImage         string
@@ -58,14 +43,11 @@ type app struct {
 	appImage             *appImage
 	appImageOnce         *sync.Once
 	hasService           bool
-	maxObservedPodStatus podStatus
+	maxObservedPodStatus k8sUtil.PodStatus // the pod status of the app with respect to depends_on, see updateAppFromReplicas.
 	name                 string
 	nameEncoded          string
-}
-
-type hostAliasesOrError struct {
-	v   []v1.HostAlias
-	err error
+	replicas             []replica
+	terminated           bool // whether the containers of all pods of the app exited, only tracked if AbortOnContainerExit is set.
 }
 
 type localImagesCacheOrError struct {
@@ -75,19 +57,34 @@ type localImagesCacheOrError struct {
 }
 
 type upRunner struct {
-	apps                  map[string]*app
-	appsThatNeedToBeReady map[*app]bool
-	appsWithoutPods       map[*app]bool
-	cfg                   *config.Config
-	ctx                   context.Context
-	dockerClient          *dockerClient.Client
-	localImagesCache      localImagesCacheOrError
-	localImagesCacheOnce  *sync.Once
-	k8sClientset          *kubernetes.Clientset
-	k8sServiceClient      clientV1.ServiceInterface
-	k8sPodClient          clientV1.PodInterface
-	hostAliasesOnce       *sync.Once
-	hostAliases           hostAliasesOrError
+	anchor                         *v1.ConfigMap
+	apps                           map[string]*app
+	appsThatNeedToBeReady          map[*app]bool
+	appsWithoutPods                map[*app]bool
+	cfg                            *config.Config
+	ctx                            context.Context
+	dockerClient                   *dockerClient.Client
+	exitApp                        *app
+	exitCode                       int
+	exitCodeFromApp                *app
+	exitCodeFromLogs               chan error
+	exitCodeFromLogsStop           chan struct{}
+	gcAnnotations                  map[string]string
+	localImagesCache               localImagesCacheOrError
+	localImagesCacheOnce           *sync.Once
+	k8sClientset                   *kubernetes.Clientset
+	k8sConfigMapClient             clientV1.ConfigMapInterface
+	k8sDeploymentClient            clientAppsV1.DeploymentInterface
+	k8sJobClient                   clientBatchV1.JobInterface
+	k8sNetworkPolicyClient         networkingV1.NetworkPolicyInterface
+	k8sPersistentVolumeClaimClient clientV1.PersistentVolumeClaimInterface
+	k8sServiceClient               clientV1.ServiceInterface
+	k8sPodClient                   clientV1.PodInterface
+	k8sStatefulSetClient           clientAppsV1.StatefulSetInterface
+	opts                           *Options
+	serviceApps                    map[*app]bool // the apps whose services and networks are created, or nil for all apps.
+	servicesOnce                   *sync.Once
+	servicesErr                    error
 }
 
 func (u *upRunner) initKubernetesClientset() error {
@@ -96,8 +93,14 @@ func (u *upRunner) initKubernetesClientset() error {
 		return err
 	}
 	u.k8sClientset = k8sClientset
+	u.k8sConfigMapClient = u.k8sClientset.CoreV1().ConfigMaps(u.cfg.Namespace)
+	u.k8sDeploymentClient = u.k8sClientset.AppsV1().Deployments(u.cfg.Namespace)
+	u.k8sJobClient = u.k8sClientset.BatchV1().Jobs(u.cfg.Namespace)
+	u.k8sNetworkPolicyClient = u.k8sClientset.NetworkingV1().NetworkPolicies(u.cfg.Namespace)
+	u.k8sPersistentVolumeClaimClient = u.k8sClientset.CoreV1().PersistentVolumeClaims(u.cfg.Namespace)
 	u.k8sServiceClient = u.k8sClientset.CoreV1().Services(u.cfg.Namespace)
 	u.k8sPodClient = u.k8sClientset.CoreV1().Pods(u.cfg.Namespace)
+	u.k8sStatefulSetClient = u.k8sClientset.AppsV1().StatefulSets(u.cfg.Namespace)
 	return nil
 }
 
@@ -139,6 +142,7 @@ func (u *upRunner) initApps() error {
 			appImageOnce: &sync.Once{},
 			name:         name,
 			nameEncoded:  k8sUtil.EncodeName(name),
+			replicas:     make([]replica, dcService.Replicas),
 		}
 		u.appsWithoutPods[app] = true
 		app.hasService = len(dcService.Ports) > 0
@@ -159,13 +163,18 @@ func (u *upRunner) initResourceObjectMeta(objectMeta *metav1.ObjectMeta, nameEnc
 	if objectMeta.Annotations == nil {
 		objectMeta.Annotations = map[string]string{}
 	}
-	objectMeta.Annotations[annotationName] = name
+	objectMeta.Annotations[k8sUtil.AnnotationName] = name
+	u.stampObjectMeta(objectMeta)
 }
 
 func (u *upRunner) getAppImage(app *app) (*config.Healthcheck, string, error) {
-	sourceImage := u.cfg.CanonicalComposeFile.Services[app.name].Image
+	dcService := u.cfg.CanonicalComposeFile.Services[app.name]
+	if dcService.Build != nil {
+		return u.buildAppImage(app, dcService)
+	}
+	sourceImage := dcService.Image
 	if len(sourceImage) == 0 {
-		return nil, "", fmt.Errorf("docker compose service %s has no image or image is the empty string, and building images is not supported", app.name)
+		return nil, "", fmt.Errorf("docker compose service %s has no image or image is the empty string, and has no build context", app.name)
 	}
 	localImageIDSet, err := u.getLocalImageIDSet()
 	if err != nil {
@@ -184,7 +193,7 @@ func (u *upRunner) getAppImage(app *app) (*config.Healthcheck, string, error) {
 	var podImage string
 	if len(sourceImageID) == 0 {
 		if !sourceImageIsNamed {
-			return nil, "", fmt.Errorf("could not find image %s locally, and building images is not supported", sourceImage)
+			return nil, "", fmt.Errorf("could not find image %s locally, and the image reference is not named so it cannot be pulled", sourceImage)
 		}
 		digest, err := pullImageWithLogging(u.ctx, u.dockerClient, app.name, sourceImageRef.String())
 		if err != nil {
@@ -204,19 +213,10 @@ func (u *upRunner) getAppImage(app *app) (*config.Healthcheck, string, error) {
 		return nil, "", err
 	}
 	if u.cfg.PushImages != nil {
-		destinationImage := fmt.Sprintf("%s/%s/%s", u.cfg.PushImages.DockerRegistry, u.cfg.Namespace, app.nameEncoded)
-		destinationImagePush := destinationImage + ":latest"
-		err = u.dockerClient.ImageTag(u.ctx, sourceImageID, destinationImagePush)
+		podImage, err = u.pushAppImage(app, sourceImageID)
 		if err != nil {
 			return nil, "", err
 		}
-		digest, err := pushImageWithLogging(u.ctx, u.dockerClient, app.name,
-			destinationImagePush,
-			u.cfg.KubeConfig.BearerToken)
-		if err != nil {
-			return nil, "", err
-		}
-		podImage = destinationImage + "@" + digest
 	} else if len(podImage) == 0 {
 		if !sourceImageIsNamed {
 			// TODO https://github.com/jbrekelmans/kube-compose/issues/6
@@ -228,6 +228,46 @@ func (u *upRunner) getAppImage(app *app) (*config.Healthcheck, string, error) {
 	return imageHealthcheck, podImage, err
 }
 
+// buildAppImage builds the image of an app from its build context, and pushes it.
+// Like docker-compose, the built image is tagged with the docker compose service's image (if any).
+func (u *upRunner) buildAppImage(app *app, dcService *config.Service) (*config.Healthcheck, string, error) {
+	if u.cfg.PushImages == nil {
+		return nil, "", fmt.Errorf("docker compose service %s has a build context, but images can only be built if pushing of images is enabled", app.name)
+	}
+	sourceImage := dcService.Image
+	if len(sourceImage) == 0 {
+		sourceImage = fmt.Sprintf("kube-compose/%s:%s", app.nameEncoded, u.cfg.EnvironmentID)
+	}
+	sourceImageID, inspectRaw, err := buildImageWithLogging(u.ctx, u.dockerClient, app.name, sourceImage, dcService.Build)
+	if err != nil {
+		return nil, "", err
+	}
+	podImage, err := u.pushAppImage(app, sourceImageID)
+	if err != nil {
+		return nil, "", err
+	}
+	imageHealthcheck, err := inspectImageRawParseHealthcheck(inspectRaw)
+	return imageHealthcheck, podImage, err
+}
+
+// pushAppImage pushes a local image to the docker registry configured by x-kube-compose.push_images, and returns the image reference
+// with digest for the pod.
+func (u *upRunner) pushAppImage(app *app, sourceImageID string) (string, error) {
+	destinationImage := fmt.Sprintf("%s/%s/%s", u.cfg.PushImages.DockerRegistry, u.cfg.Namespace, app.nameEncoded)
+	destinationImagePush := destinationImage + ":latest"
+	err := u.dockerClient.ImageTag(u.ctx, sourceImageID, destinationImagePush)
+	if err != nil {
+		return "", err
+	}
+	digest, err := pushImageWithLogging(u.ctx, u.dockerClient, app.name,
+		destinationImagePush,
+		u.cfg.KubeConfig.BearerToken)
+	if err != nil {
+		return "", err
+	}
+	return destinationImage + "@" + digest, nil
+}
+
 func (u *upRunner) getAppImageOnce(app *app) (*config.Healthcheck, string, error) {
 	app.appImageOnce.Do(func() {
 		imageHealthcheck, podImage, err := u.getAppImage(app)
@@ -242,7 +282,7 @@ func (u *upRunner) getAppImageOnce(app *app) (*config.Healthcheck, string, error
 
 func (u *upRunner) findAppFromResourceObjectMeta(objectMeta *metav1.ObjectMeta) (*app, error) {
 	if objectMeta.Annotations != nil {
-		if name, ok := objectMeta.Annotations[annotationName]; ok {
+		if name, ok := objectMeta.Annotations[k8sUtil.AnnotationName]; ok {
 			if app, ok := u.apps[name]; ok {
 				return app, nil
 			}
@@ -272,7 +312,7 @@ func (u *upRunner) waitForServiceClusterIPUpdate(service *v1.Service) (*app, err
 func (u *upRunner) waitForServiceClusterIPCountRemaining() int {
 	remaining := 0
 	for _, app := range u.apps {
-		if app.hasService && len(app.serviceClusterIP) == 0 {
+		if app.hasService && u.isServiceApp(app) && len(app.serviceClusterIP) == 0 {
 			remaining++
 		}
 	}
@@ -283,7 +323,7 @@ func (u *upRunner) waitForServiceClusterIP(expected int) error {
 	listOptions := metav1.ListOptions{
 		LabelSelector: u.cfg.EnvironmentLabel + "=" + u.cfg.EnvironmentID,
 	}
-	serviceList, err := u.k8sServiceClient.List(listOptions)
+	serviceList, err := u.k8sServiceClient.List(u.ctx, listOptions)
 	if err != nil {
 		return err
 	}
@@ -302,18 +342,18 @@ func (u *upRunner) waitForServiceClusterIP(expected int) error {
 		return nil
 	}
 	fmt.Printf("waiting for cluster IP assignment (%d/%d)\n", expected-remaining, expected)
-	listOptions.ResourceVersion = serviceList.ResourceVersion
-	listOptions.Watch = true
-	watch, err := u.k8sServiceClient.Watch(listOptions)
+	list := func(ctx context.Context, listOptions metav1.ListOptions) (runtime.Object, error) {
+		return u.k8sServiceClient.List(ctx, listOptions)
+	}
+	w, err := newWatcher(u.ctx, listOptions, serviceList, list, u.k8sServiceClient.Watch)
 	if err != nil {
 		return err
 	}
-	defer watch.Stop()
-	eventChannel := watch.ResultChan()
+	defer w.stop()
 	for {
-		event, ok := <-eventChannel
-		if !ok {
-			return fmt.Errorf("channel unexpectedly closed")
+		event, err := w.next()
+		if err != nil {
+			return err
 		}
 		if event.Type == "ADDED" || event.Type == "MODIFIED" {
 			service := event.Object.(*v1.Service)
@@ -345,65 +385,110 @@ func (u *upRunner) waitForServiceClusterIP(expected int) error {
 	return nil
 }
 
-func (u *upRunner) createServicesAndGetPodHostAliases() ([]v1.HostAlias, error) {
+func (u *upRunner) newService(app *app) *v1.Service {
+	dcService := u.cfg.CanonicalComposeFile.Services[app.name]
+	servicePorts := make([]v1.ServicePort, len(dcService.Ports))
+	for i, port := range dcService.Ports {
+		servicePorts[i] = v1.ServicePort{
+			Name:       fmt.Sprintf("%s%d", port.Protocol, port.Internal),
+			Port:       port.Internal,
+			Protocol:   v1.Protocol(strings.ToUpper(port.Protocol)),
+			TargetPort: intstr.FromInt(int(port.Internal)),
+		}
+	}
+	service := &v1.Service{
+		Spec: v1.ServiceSpec{
+			Ports: servicePorts,
+			Selector: map[string]string{
+				"app":                  app.nameEncoded,
+				u.cfg.EnvironmentLabel: u.cfg.EnvironmentID,
+			},
+			// This is the default value.
+			// Type: v1.ServiceType("ClusterIP"),
+		},
+	}
+	u.initResourceObjectMeta(&service.ObjectMeta, app.nameEncoded, app.name)
+	return service
+}
+
+// newHostAliases returns the host aliases of the pod of app1, which map the name of each app with a service to the service's cluster IP.
+// Like docker, an app is only resolvable from apps it shares a network with, and its aliases on those networks resolve as well.
+func (u *upRunner) newHostAliases(app1 *app) []v1.HostAlias {
+	var hostAliases []v1.HostAlias
+	serviceNetworks1 := u.cfg.CanonicalComposeFile.Services[app1.name].Networks
+	for _, app2 := range u.apps {
+		if !app2.hasService || len(app2.serviceClusterIP) == 0 {
+			// The service of app2 was not created, see serviceApps.
+			continue
+		}
+		hostnames := []string{}
+		shareNetwork := false
+		for name, serviceNetwork2 := range u.cfg.CanonicalComposeFile.Services[app2.name].Networks {
+			if _, ok := serviceNetworks1[name]; ok {
+				shareNetwork = true
+				hostnames = append(hostnames, serviceNetwork2.Aliases...)
+			}
+		}
+		if !shareNetwork {
+			continue
+		}
+		sort.Strings(hostnames)
+		hostAliases = append(hostAliases, v1.HostAlias{
+			IP:        app2.serviceClusterIP,
+			Hostnames: append([]string{app2.name}, removeDuplicates(hostnames)...),
+		})
+	}
+	sort.Slice(hostAliases, func(i, j int) bool {
+		return hostAliases[i].Hostnames[0] < hostAliases[j].Hostnames[0]
+	})
+	return hostAliases
+}
+
+// isServiceApp returns true if the service and networks of app are created, see serviceApps.
+func (u *upRunner) isServiceApp(app *app) bool {
+	return u.serviceApps == nil || u.serviceApps[app]
+}
+
+// createServices creates the services of all apps (or those of serviceApps), and waits until they have a cluster IP so that host aliases
+// can be computed.
+func (u *upRunner) createServices() error {
+	if u.cfg.NetworkPolicies {
+		err := u.createNetworkPolicies()
+		if err != nil {
+			return err
+		}
+	}
+	if u.needsHeadlessService() {
+		err := u.createHeadlessService()
+		if err != nil {
+			return err
+		}
+	}
+	if u.cfg.DNS.Mode == config.DNSModeSubdomain {
+		// Names are resolved via cluster DNS, so pods do not need to wait for cluster IPs.
+		return nil
+	}
 	expectedServiceCount := 0
 	for _, app := range u.apps {
-		if app.hasService {
+		if app.hasService && u.isServiceApp(app) {
 			expectedServiceCount++
-			dcService := u.cfg.CanonicalComposeFile.Services[app.name]
-			servicePorts := make([]v1.ServicePort, len(dcService.Ports))
-			for i, port := range dcService.Ports {
-				servicePorts[i] = v1.ServicePort{
-					Name:       fmt.Sprintf("%s%d", port.Protocol, port.Internal),
-					Port:       port.Internal,
-					Protocol:   v1.Protocol(strings.ToUpper(port.Protocol)),
-					TargetPort: intstr.FromInt(int(port.Internal)),
-				}
-			}
-			service := &v1.Service{
-				Spec: v1.ServiceSpec{
-					Ports: servicePorts,
-					Selector: map[string]string{
-						"app":                  app.nameEncoded,
-						u.cfg.EnvironmentLabel: u.cfg.EnvironmentID,
-					},
-					// This is the default value.
-					// Type: v1.ServiceType("ClusterIP"),
-				},
-			}
-			u.initResourceObjectMeta(&service.ObjectMeta, app.nameEncoded, app.name)
-			_, err := u.k8sServiceClient.Create(service)
+			service := u.newService(app)
+			_, err := u.k8sServiceClient.Create(u.ctx, service, metav1.CreateOptions{})
 
 			if k8sError.IsAlreadyExists(err) {
 				fmt.Printf("app %s: service %s already exists\n", app.name, service.ObjectMeta.Name)
 			} else if err != nil {
-				return nil, err
+				return err
 			} else {
 				fmt.Printf("app %s: created service %s\n", app.name, service.ObjectMeta.Name)
 			}
 		}
 	}
-	if expectedServiceCount == 0 {
-		return nil, nil
-	}
-	err := u.waitForServiceClusterIP(expectedServiceCount)
-	if err != nil {
-		return nil, err
-	}
-	hostAliases := make([]v1.HostAlias, expectedServiceCount)
-	i := 0
-	for _, app := range u.apps {
-		if app.hasService {
-			hostAliases[i] = v1.HostAlias{
-				IP: app.serviceClusterIP,
-				Hostnames: []string{
-					app.name,
-				},
-			}
-			i++
-		}
+	if expectedServiceCount == 0 && u.serviceApps == nil {
+		return nil
 	}
-	return hostAliases, nil
+	// With serviceApps, this also gets the cluster IPs of the existing services of other apps.
+	return u.waitForServiceClusterIP(expectedServiceCount)
 }
 
 func (u *upRunner) initLocalImages() error {
@@ -436,22 +521,16 @@ func (u *upRunner) getLocalImageIDSet() (*digestset.Set, error) {
 	return u.localImagesCache.imageIDSet, nil
 }
 
-func (u *upRunner) createServicesAndGetPodHostAliasesOnce() ([]v1.HostAlias, error) {
-	u.hostAliasesOnce.Do(func() {
-		hostAliases, err := u.createServicesAndGetPodHostAliases()
-		u.hostAliases = hostAliasesOrError{
-			v:   hostAliases,
-			err: err,
-		}
+func (u *upRunner) createServicesOnce() error {
+	u.servicesOnce.Do(func() {
+		u.servicesErr = u.createServices()
 	})
-	return u.hostAliases.v, u.hostAliases.err
+	return u.servicesErr
 }
 
-func (u *upRunner) createPod(app *app) (*v1.Pod, error) {
-	imageHealthcheck, podImage, err := u.getAppImageOnce(app)
-	if err != nil {
-		return nil, err
-	}
+// newPod returns the pod of app with the labels of its networks but otherwise without object metadata, and the ConfigMaps of its
+// read-only bind mounts (which must be created before the pod). The cluster IPs of services must be known, see createServicesOnce.
+func (u *upRunner) newPod(app *app, imageHealthcheck *config.Healthcheck, podImage string) (*v1.Pod, []*v1.ConfigMap, error) {
 	dcService := u.cfg.CanonicalComposeFile.Services[app.name]
 
 	// We convert the image/docker-compose healthcheck to a readiness probe to implement
@@ -460,12 +539,28 @@ func (u *upRunner) createPod(app *app) (*v1.Pod, error) {
 	// https://stackoverflow.com/questions/41475088/when-to-use-docker-healthcheck-vs-livenessprobe-readinessprobe
 	// ... so we're not doubling up on healthchecks.
 	// We accept that this may lead to calls failing due to removal backend pods from load balancers.
-	var readinessProbe *v1.Probe
+	var healthcheck *config.Healthcheck
 	if !dcService.HealthcheckDisabled {
-		if dcService.Healthcheck != nil {
-			readinessProbe = createReadinessProbeFromDockerHealthcheck(dcService.Healthcheck)
-		} else if imageHealthcheck != nil {
-			readinessProbe = createReadinessProbeFromDockerHealthcheck(imageHealthcheck)
+		healthcheck = dcService.Healthcheck
+		if healthcheck == nil {
+			healthcheck = imageHealthcheck
+		}
+	}
+	// A probe declared explicitly in the x-kube-compose of the service takes precedence, also if the healthcheck is disabled.
+	probe := dcService.Probe
+	if probe == nil && healthcheck != nil && u.cfg.Probes.Translate {
+		probe = config.TranslateHealthcheck(healthcheck)
+	}
+	readinessProbe := createReadinessProbeFromDockerHealthcheck(healthcheck, probe)
+	var livenessProbe, startupProbe *v1.Probe
+	if u.cfg.Probes.Startup {
+		startupProbe = createStartupProbeFromDockerHealthcheck(healthcheck, probe)
+	}
+	if u.cfg.Probes.Liveness {
+		livenessProbe = createLivenessProbeFromDockerHealthcheck(healthcheck, probe)
+		if livenessProbe != nil && startupProbe != nil {
+			// The startup probe already covers the start period.
+			livenessProbe.InitialDelaySeconds = 0
 		}
 	}
 	var containerPorts []v1.ContainerPort
@@ -490,75 +585,103 @@ func (u *upRunner) createPod(app *app) (*v1.Pod, error) {
 			}
 			i++
 		}
+		// Sort so that the pod spec is deterministic.
+		sort.Slice(envVars, func(i, j int) bool {
+			return envVars[i].Name < envVars[j].Name
+		})
 	}
-	hostAliases, err := u.createServicesAndGetPodHostAliasesOnce()
+	volumes, volumeMounts, configMaps, err := u.newPodVolumes(app)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	labels, err := u.newPodNetworkLabels(app)
+	if err != nil {
+		return nil, nil, err
+	}
+	var hostAliases []v1.HostAlias
+	if u.cfg.DNS.Mode != config.DNSModeSubdomain {
+		hostAliases = u.newHostAliases(app)
 	}
 
 	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: labels,
+		},
 		Spec: v1.PodSpec{
 			AutomountServiceAccountToken: newFalsePointer(),
 			Containers: []v1.Container{
 				v1.Container{
+					// Like Docker, the entrypoint replaces the image's entrypoint and the command replaces the image's command.
+					// https://kubernetes.io/docs/tasks/inject-data-application/define-command-argument-container/#notes
+					Args:            dcService.Command,
 					Command:         dcService.Entrypoint,
 					Env:             envVars,
 					Image:           podImage,
 					ImagePullPolicy: v1.PullAlways,
+					LivenessProbe:   livenessProbe,
 					Name:            app.nameEncoded,
 					Ports:           containerPorts,
 					ReadinessProbe:  readinessProbe,
+					StartupProbe:    startupProbe,
+					VolumeMounts:    volumeMounts,
 					WorkingDir:      dcService.WorkingDir,
 				},
 			},
 			HostAliases:   hostAliases,
-			RestartPolicy: v1.RestartPolicyNever,
+			RestartPolicy: newRestartPolicy(dcService),
+			Volumes:       volumes,
 		},
 	}
-	u.initResourceObjectMeta(&pod.ObjectMeta, app.nameEncoded, app.name)
-	podServer, err := u.k8sPodClient.Create(pod)
+	u.initPodDNS(app, &pod.Spec)
+	err = initPodConfig(pod, &dcService.Pod)
 	if err != nil {
-		return podServer, err
+		return nil, nil, err
 	}
-	u.appsThatNeedToBeReady[app] = true
-	return podServer, nil
+	return pod, configMaps, nil
 }
 
-func parsePodStatus(pod *v1.Pod) (podStatus, error) {
-	for _, condition := range pod.Status.Conditions {
-		if condition.Type == v1.PodReady && condition.Status == v1.ConditionTrue {
-			return podStatusReady, nil
-		}
+// newPodWithDependencies returns the pod of app (see newPod), after resolving its image and creating the services of all apps.
+func (u *upRunner) newPodWithDependencies(app *app) (*v1.Pod, []*v1.ConfigMap, error) {
+	imageHealthcheck, podImage, err := u.getAppImageOnce(app)
+	if err != nil {
+		return nil, nil, err
 	}
-	runningCount := 0
-	for _, containerStatus := range pod.Status.ContainerStatuses {
-		t := containerStatus.State.Terminated
-		if t != nil {
-			return podStatusOther, fmt.Errorf("aborting because container %s of pod %s terminated (code=%d,signal=%d,reason=%s): %s",
-				containerStatus.Name,
-				pod.ObjectMeta.Name,
-				t.ExitCode,
-				t.Signal,
-				t.Reason,
-				t.Message,
-			)
-		}
+	err = u.createServicesOnce()
+	if err != nil {
+		return nil, nil, err
+	}
+	return u.newPod(app, imageHealthcheck, podImage)
+}
 
-		if w := containerStatus.State.Waiting; w != nil && w.Reason == "ErrImagePull" {
-			return podStatusOther, fmt.Errorf("aborting because container %s of pod %s could not pull image: %s",
-				containerStatus.Name,
-				pod.ObjectMeta.Name,
-				w.Message,
-			)
-		}
-		if containerStatus.State.Running != nil {
-			runningCount++
+// createPods creates the pods of all replicas of app, or the workload that manages them, where reason explains why the pods are created.
+func (u *upRunner) createPods(app *app, reason string) error {
+	pod, configMaps, err := u.newPodWithDependencies(app)
+	if err != nil {
+		return err
+	}
+	err = u.createConfigMaps(app, configMaps)
+	if err != nil {
+		return err
+	}
+	if w := u.newWorkload(app, pod); w != nil {
+		err = u.createWorkload(app, w, reason)
+		if err != nil {
+			return err
 		}
+		u.appsThatNeedToBeReady[app] = true
+		return nil
 	}
-	if runningCount == len(pod.Status.ContainerStatuses) {
-		return podStatusStarted, nil
+	for i := range app.replicas {
+		replicaPod := pod.DeepCopy()
+		u.initReplicaObjectMeta(&replicaPod.ObjectMeta, app, i)
+		_, err = u.k8sPodClient.Create(u.ctx, replicaPod, metav1.CreateOptions{})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("app %s: created pod %s because %s\n", app.name, replicaPod.ObjectMeta.Name, reason)
 	}
-	return podStatusOther, nil
+	u.appsThatNeedToBeReady[app] = true
+	return nil
 }
 
 func (u *upRunner) updateAppMaxObservedPodStatus(pod *v1.Pod) error {
@@ -570,31 +693,103 @@ func (u *upRunner) updateAppMaxObservedPodStatus(pod *v1.Pod) error {
 	if app == nil {
 		return nil
 	}
-	podStatus, err := parsePodStatus(pod)
+	replica, err := findReplica(app, &pod.ObjectMeta)
+	if err != nil {
+		return err
+	}
+	if replica == nil {
+		// All replicas of app are tracked by other pods of its workload, e.g. while a Deployment replaces a pod.
+		return nil
+	}
+	err = u.checkRestarts(app, pod)
+	if err != nil {
+		return err
+	}
+	if u.opts.AbortOnContainerExit {
+		if terminated := k8sUtil.GetTerminatedContainerState(pod); terminated != nil {
+			u.updateAppTerminated(app, replica, pod, terminated)
+			u.startExitCodeFromLogs(app, pod)
+			return nil
+		}
+	}
+	podStatus, err := k8sUtil.ParsePodStatus(pod, u.isExpectedToComplete(app))
 	if err != nil {
 		return err
 	}
-	if podStatus > app.maxObservedPodStatus {
-		app.maxObservedPodStatus = podStatus
-		fmt.Printf("app %s: pod status %s\n", app.name, &app.maxObservedPodStatus)
+	if podStatus > replica.maxObservedPodStatus {
+		replica.maxObservedPodStatus = podStatus
+		u.updateAppFromReplicas(app)
+	}
+	if u.opts.AbortOnContainerExit {
+		u.startExitCodeFromLogs(app, pod)
 	}
 	return nil
 }
 
+func isDependencyConditionMet(dependency *app, healthiness config.ServiceHealthiness) bool {
+	switch healthiness {
+	case config.ServiceCompletedSuccessfully:
+		return dependency.maxObservedPodStatus == k8sUtil.PodStatusCompleted
+	case config.ServiceHealthy:
+		// A pod that completed is no longer healthy.
+		return dependency.maxObservedPodStatus == k8sUtil.PodStatusReady
+	}
+	// A pod that is ready or completed has also started.
+	return dependency.maxObservedPodStatus >= k8sUtil.PodStatusStarted
+}
+
+// isAppReady returns true if the pods of all replicas of app are ready, or completed if app is expected to complete (see
+// isExpectedToComplete), because pods that completed do not become ready.
+func (u *upRunner) isAppReady(app *app) bool {
+	podStatus := app.minReplicaPodStatus()
+	return podStatus == k8sUtil.PodStatusReady || (podStatus == k8sUtil.PodStatusCompleted && u.isExpectedToComplete(app))
+}
+
+// errorWaiting returns an error that describes which apps are waiting for which dependencies, and which pods are not ready yet. This
+// is used when waiting for pods is interrupted, for example because the deadline of the context was exceeded.
+func (u *upRunner) errorWaiting(err error) error {
+	lines := []string{}
+	for app1 := range u.appsWithoutPods {
+		waitingFor := []string{}
+		for dcService, healthiness := range u.cfg.CanonicalComposeFile.Services[app1.name].DependsOn {
+			if isDependencyConditionMet(u.apps[dcService.ServiceName], healthiness) {
+				continue
+			}
+			switch healthiness {
+			case config.ServiceCompletedSuccessfully:
+				waitingFor = append(waitingFor, dcService.ServiceName+" to complete successfully")
+			case config.ServiceHealthy:
+				waitingFor = append(waitingFor, dcService.ServiceName+" to be ready")
+			default:
+				waitingFor = append(waitingFor, dcService.ServiceName+" to be running")
+			}
+		}
+		sort.Strings(waitingFor)
+		lines = append(lines, fmt.Sprintf("app %s: waiting for %s", app1.name, strings.Join(waitingFor, ", ")))
+	}
+	for app1 := range u.appsThatNeedToBeReady {
+		if u.opts.AbortOnContainerExit {
+			if !app1.terminated {
+				lines = append(lines, fmt.Sprintf("app %s: waiting for its container to exit (pod status %s)", app1.name, &app1.maxObservedPodStatus))
+			}
+		} else if podStatus := app1.minReplicaPodStatus(); !u.isAppReady(app1) {
+			lines = append(lines, fmt.Sprintf("app %s: waiting for its pod to be ready (pod status %s)", app1.name, &podStatus))
+		}
+	}
+	if len(lines) == 0 {
+		return err
+	}
+	sort.Strings(lines)
+	return fmt.Errorf("%v while waiting for pods:\n%s", err, strings.Join(lines, "\n"))
+}
+
 func (u *upRunner) createPodsIfNeeded() error {
 	for app1 := range u.appsWithoutPods {
 		dependsOn := u.cfg.CanonicalComposeFile.Services[app1.name].DependsOn
 		createPod := true
 		for dcService, healthiness := range dependsOn {
-			app2 := u.apps[dcService.ServiceName]
-			if healthiness == config.ServiceHealthy {
-				if app2.maxObservedPodStatus != podStatusReady {
-					createPod = false
-				}
-			} else {
-				if app2.maxObservedPodStatus != podStatusStarted {
-					createPod = false
-				}
+			if !isDependencyConditionMet(u.apps[dcService.ServiceName], healthiness) {
+				createPod = false
 			}
 		}
 		if createPod {
@@ -606,19 +801,21 @@ func (u *upRunner) createPodsIfNeeded() error {
 					reason.WriteString(", ")
 				}
 				reason.WriteString(dcService.ServiceName)
-				if healthiness == config.ServiceHealthy {
+				switch healthiness {
+				case config.ServiceCompletedSuccessfully:
+					reason.WriteString(": completed")
+				case config.ServiceHealthy:
 					reason.WriteString(": ready")
-				} else {
+				default:
 					reason.WriteString(": running")
 				}
 				comma = true
 			}
 			reason.WriteString(")")
-			pod, err := u.createPod(app1)
+			err := u.createPods(app1, reason.String())
 			if err != nil {
 				return err
 			}
-			fmt.Printf("app %s: created pod %s because %s\n", app1.name, pod.ObjectMeta.Name, reason.String())
 			delete(u.appsWithoutPods, app1)
 		}
 	}
@@ -630,10 +827,22 @@ func (u *upRunner) run() error {
 	if err != nil {
 		return err
 	}
+	err = u.checkReplicas()
+	if err != nil {
+		return err
+	}
+	err = u.initExitCodeFrom()
+	if err != nil {
+		return err
+	}
 	err = u.initKubernetesClientset()
 	if err != nil {
 		return err
 	}
+	err = u.createAnchor()
+	if err != nil {
+		return err
+	}
 	// Initialize docker client
 	dockerClient, err := dockerClient.NewEnvClient()
 	if err != nil {
@@ -649,14 +858,17 @@ func (u *upRunner) run() error {
 	// Begin creating services and collecting their cluster IPs (we'll need this to
 	// set the hostAliases of each pod)
 	// nolint
-	go u.createServicesAndGetPodHostAliasesOnce()
+	go u.createServicesOnce()
+	err = u.createPersistentVolumeClaims()
+	if err != nil {
+		return err
+	}
 	for app := range u.appsWithoutPods {
 		if len(u.cfg.CanonicalComposeFile.Services[app.name].DependsOn) == 0 {
-			pod, err := u.createPod(app)
+			err := u.createPods(app, "all its dependency conditions are met")
 			if err != nil {
 				return err
 			}
-			fmt.Printf("app %s: created pod %s because all its dependency conditions are met\n", app.name, pod.ObjectMeta.Name)
 			delete(u.appsWithoutPods, app)
 		}
 	}
@@ -664,7 +876,7 @@ func (u *upRunner) run() error {
 	listOptions := metav1.ListOptions{
 		LabelSelector: u.cfg.EnvironmentLabel + "=" + u.cfg.EnvironmentID,
 	}
-	podList, err := u.k8sPodClient.List(listOptions)
+	podList, err := u.k8sPodClient.List(u.ctx, listOptions)
 	if err != nil {
 		return err
 	}
@@ -678,18 +890,21 @@ func (u *upRunner) run() error {
 	if err != nil {
 		return err
 	}
-	listOptions.ResourceVersion = podList.ResourceVersion
-	listOptions.Watch = true
-	watch, err := u.k8sPodClient.Watch(listOptions)
+	if u.opts.AbortOnContainerExit && u.isDoneWaitingForExit() {
+		return u.waitForExitCodeFromLogs()
+	}
+	list := func(ctx context.Context, listOptions metav1.ListOptions) (runtime.Object, error) {
+		return u.k8sPodClient.List(ctx, listOptions)
+	}
+	w, err := newWatcher(u.ctx, listOptions, podList, list, u.k8sPodClient.Watch)
 	if err != nil {
 		return err
 	}
-	defer watch.Stop()
-	eventChannel := watch.ResultChan()
+	defer w.stop()
 	for {
-		event, ok := <-eventChannel
-		if !ok {
-			return fmt.Errorf("channel unexpectedly closed")
+		event, err := w.next()
+		if err != nil {
+			return u.errorWaiting(err)
 		}
 		if event.Type == "ADDED" || event.Type == "MODIFIED" {
 			pod := event.Object.(*v1.Pod)
@@ -704,7 +919,10 @@ func (u *upRunner) run() error {
 				return err
 			}
 			if app != nil {
-				return errorResourcesModifiedExternally()
+				err = removeWorkloadReplica(app, &pod.ObjectMeta)
+				if err != nil {
+					return err
+				}
 			}
 		} else {
 			return fmt.Errorf("got unexpected error event from channel: %+v", event.Object)
@@ -714,9 +932,16 @@ func (u *upRunner) run() error {
 		if err != nil {
 			return err
 		}
+		if u.opts.AbortOnContainerExit {
+			if u.isDoneWaitingForExit() {
+				return u.waitForExitCodeFromLogs()
+			}
+			continue
+		}
+		// Regardless of replicas_ready, all replicas must be ready.
 		allPodsReady := true
 		for app := range u.appsThatNeedToBeReady {
-			if app.maxObservedPodStatus != podStatusReady {
+			if !u.isAppReady(app) {
 				allPodsReady = false
 			}
 		}
@@ -728,14 +953,17 @@ func (u *upRunner) run() error {
 	return nil
 }
 
-// Run runs an operation similar docker-compose up against a Kubernetes cluster.
-func Run(cfg *config.Config) error {
-	// TODO https://github.com/jbrekelmans/kube-compose/issues/2 accept context as a parameter
+// Run runs an operation similar docker-compose up against a Kubernetes cluster. Pulling, pushing and waiting for pods is aborted when
+// ctx is done. The returned exit code is only non-zero if opts.AbortOnContainerExit or opts.ExitCodeFrom is set, see Options.
+func Run(ctx context.Context, cfg *config.Config, opts *Options) (int, error) {
 	u := &upRunner{
 		cfg:                  cfg,
-		ctx:                  context.Background(),
-		hostAliasesOnce:      &sync.Once{},
+		ctx:                  ctx,
 		localImagesCacheOnce: &sync.Once{},
+		opts:                 opts,
+		servicesOnce:         &sync.Once{},
 	}
-	return u.run()
+	u.initGCAnnotations(opts.TTL, opts.CIJobID)
+	err := u.run()
+	return u.exitCode, err
 }
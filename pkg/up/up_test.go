@@ -0,0 +1,103 @@
+package up
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/jbrekelmans/kube-compose/pkg/config"
+	k8sUtil "github.com/jbrekelmans/kube-compose/pkg/k8s"
+)
+
+const testEnvironmentID = "test123"
+
+// newTestUpRunner returns an upRunner (with initialized apps) of the docker compose file with contents composeFile.
+func newTestUpRunner(t *testing.T, composeFile string) *upRunner {
+	dir, err := ioutil.TempDir("", "kube-compose-up")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "docker-compose.yml")
+	err = ioutil.WriteFile(fileName, []byte(composeFile), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.New([]string{fileName}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.EnvironmentID = testEnvironmentID
+	cfg.Namespace = "default"
+	u := &upRunner{
+		cfg:                  cfg,
+		ctx:                  context.Background(),
+		localImagesCacheOnce: &sync.Once{},
+		servicesOnce:         &sync.Once{},
+	}
+	err = u.initApps()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestIsDependencyConditionMet(t *testing.T) {
+	testCases := []struct {
+		podStatus   k8sUtil.PodStatus
+		healthiness config.ServiceHealthiness
+		expected    bool
+	}{
+		{k8sUtil.PodStatusOther, config.ServiceStarted, false},
+		{k8sUtil.PodStatusStarted, config.ServiceStarted, true},
+		{k8sUtil.PodStatusReady, config.ServiceStarted, true},
+		{k8sUtil.PodStatusCompleted, config.ServiceStarted, true},
+		{k8sUtil.PodStatusStarted, config.ServiceHealthy, false},
+		{k8sUtil.PodStatusReady, config.ServiceHealthy, true},
+		{k8sUtil.PodStatusCompleted, config.ServiceHealthy, false},
+		{k8sUtil.PodStatusReady, config.ServiceCompletedSuccessfully, false},
+		{k8sUtil.PodStatusCompleted, config.ServiceCompletedSuccessfully, true},
+	}
+	for _, testCase := range testCases {
+		dependency := &app{
+			maxObservedPodStatus: testCase.podStatus,
+		}
+		if isDependencyConditionMet(dependency, testCase.healthiness) != testCase.expected {
+			t.Errorf("pod status %s and condition %s: expected %t", &testCase.podStatus, &testCase.healthiness, testCase.expected)
+		}
+	}
+}
+
+func TestIsAppReady(t *testing.T) {
+	u := newTestUpRunner(t, `version: '2.4'
+services:
+  web:
+    image: web
+  job:
+    image: job
+    x-kube-compose:
+      kind: job
+`)
+	testCases := []struct {
+		app       string
+		podStatus k8sUtil.PodStatus
+		expected  bool
+	}{
+		{"web", k8sUtil.PodStatusStarted, false},
+		{"web", k8sUtil.PodStatusReady, true},
+		{"web", k8sUtil.PodStatusCompleted, false},
+		{"job", k8sUtil.PodStatusStarted, false},
+		{"job", k8sUtil.PodStatusReady, true},
+		{"job", k8sUtil.PodStatusCompleted, true},
+	}
+	for _, testCase := range testCases {
+		app := u.apps[testCase.app]
+		app.replicas[0].maxObservedPodStatus = testCase.podStatus
+		if u.isAppReady(app) != testCase.expected {
+			t.Errorf("app %s with pod status %s: expected %t", testCase.app, &testCase.podStatus, testCase.expected)
+		}
+	}
+}
@@ -15,13 +15,23 @@ import (
 	"github.com/jbrekelmans/kube-compose/pkg/config"
 	"github.com/jbrekelmans/kube-compose/pkg/docker"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // https://docs.docker.com/engine/reference/builder/#healthcheck
 // https://kubernetes.io/docs/tasks/configure-pod-container/configure-liveness-readiness-probes/#configure-probes
-func createReadinessProbeFromDockerHealthcheck(healthcheck *config.Healthcheck) *v1.Probe {
+// If probe is not nil it replaces the command of the healthcheck, and if healthcheck is nil the default interval, timeout and retries
+// of docker healthchecks are used.
+func createReadinessProbeFromDockerHealthcheck(healthcheck *config.Healthcheck, probe *config.Probe) *v1.Probe {
 	if healthcheck == nil {
-		return nil
+		if probe == nil {
+			return nil
+		}
+		healthcheck = &config.Healthcheck{
+			Interval: config.HealthcheckDefaultInterval,
+			Retries:  config.HealthcheckDefaultRetries,
+			Timeout:  config.HealthcheckDefaultTimeout,
+		}
 	}
 
 	var retriesInt32 int32
@@ -31,39 +41,100 @@ func createReadinessProbeFromDockerHealthcheck(healthcheck *config.Healthcheck)
 		retriesInt32 = int32(healthcheck.Retries)
 	}
 
-	offset := 0
-	if healthcheck.IsShell {
-		// The Shell is hardcoded by docker to be /bin/sh
-		// Add 2 to accomodate for /bin/sh -c
-		offset = 2
-	}
-	n := len(healthcheck.Test) + offset
-	execCommand := make([]string, n)
-	if offset > 0 {
-		execCommand[0] = "/bin/sh"
-		execCommand[1] = "-c"
-	}
-	for i := offset; i < n; i++ {
-		execCommand[i] = healthcheck.Test[i-offset]
+	var handler v1.ProbeHandler
+	if probe != nil {
+		handler = createProbeHandler(probe)
+	} else {
+		offset := 0
+		if healthcheck.IsShell {
+			// The Shell is hardcoded by docker to be /bin/sh
+			// Add 2 to accomodate for /bin/sh -c
+			offset = 2
+		}
+		n := len(healthcheck.Test) + offset
+		execCommand := make([]string, n)
+		if offset > 0 {
+			execCommand[0] = "/bin/sh"
+			execCommand[1] = "-c"
+		}
+		for i := offset; i < n; i++ {
+			execCommand[i] = healthcheck.Test[i-offset]
+		}
+		handler.Exec = &v1.ExecAction{
+			Command: execCommand,
+		}
 	}
-	probe := &v1.Probe{
+	readinessProbe := &v1.Probe{
 		FailureThreshold: retriesInt32,
-		Handler: v1.Handler{
-			Exec: &v1.ExecAction{
-				Command: execCommand,
-			},
-		},
+		ProbeHandler:     handler,
 		// InitialDelaySeconds must always be zero so we start the healthcheck immediately.
 		// Irrespective of Docker's StartPeriod we should set this to zero.
-		// If this was a liveness probe we would have to set InitialDelaySeconds to StartPeriod.
+		// Liveness probes set InitialDelaySeconds to StartPeriod, see createLivenessProbeFromDockerHealthcheck.
 		InitialDelaySeconds: 0,
 
-		PeriodSeconds:  int32(math.RoundToEven(healthcheck.Interval.Seconds())),
-		TimeoutSeconds: int32(math.RoundToEven(healthcheck.Timeout.Seconds())),
+		PeriodSeconds:  durationToProbeSeconds(healthcheck.Interval),
+		TimeoutSeconds: durationToProbeSeconds(healthcheck.Timeout),
 		// This is the default value.
 		// SuccessThreshold: 1,
 	}
-	return probe
+	return readinessProbe
+}
+
+// createLivenessProbeFromDockerHealthcheck returns a liveness probe that is the same as the readiness probe. Docker ignores failures
+// during the start period of a healthcheck, so the liveness probe is delayed by the start period.
+func createLivenessProbeFromDockerHealthcheck(healthcheck *config.Healthcheck, probe *config.Probe) *v1.Probe {
+	livenessProbe := createReadinessProbeFromDockerHealthcheck(healthcheck, probe)
+	if livenessProbe != nil && healthcheck != nil && healthcheck.StartPeriod > 0 {
+		livenessProbe.InitialDelaySeconds = durationToProbeSeconds(healthcheck.StartPeriod)
+	}
+	return livenessProbe
+}
+
+// createStartupProbeFromDockerHealthcheck returns a startup probe that is the same as the readiness probe, except that it tolerates
+// failures for the start period of the healthcheck. Liveness probes do not run until the startup probe succeeded, so they need not be
+// delayed.
+func createStartupProbeFromDockerHealthcheck(healthcheck *config.Healthcheck, probe *config.Probe) *v1.Probe {
+	startupProbe := createReadinessProbeFromDockerHealthcheck(healthcheck, probe)
+	if startupProbe != nil && healthcheck != nil && healthcheck.StartPeriod > 0 {
+		startPeriodFailures := int64(math.Ceil(float64(healthcheck.StartPeriod) / float64(healthcheck.Interval)))
+		failureThreshold := int64(startupProbe.FailureThreshold) + startPeriodFailures
+		if failureThreshold > math.MaxInt32 {
+			failureThreshold = math.MaxInt32
+		}
+		startupProbe.FailureThreshold = int32(failureThreshold)
+	}
+	return startupProbe
+}
+
+// createProbeHandler returns the HTTP GET or TCP socket action of probe. Probes connect to the IP of the pod.
+func createProbeHandler(probe *config.Probe) v1.ProbeHandler {
+	if probe.HTTPGet != nil {
+		return v1.ProbeHandler{
+			HTTPGet: &v1.HTTPGetAction{
+				Path:   probe.HTTPGet.Path,
+				Port:   intstr.FromInt(int(probe.HTTPGet.Port)),
+				Scheme: v1.URIScheme(probe.HTTPGet.Scheme),
+			},
+		}
+	}
+	return v1.ProbeHandler{
+		TCPSocket: &v1.TCPSocketAction{
+			Port: intstr.FromInt(int(probe.TCPSocket.Port)),
+		},
+	}
+}
+
+// durationToProbeSeconds converts a duration of a docker healthcheck to a number of seconds of a probe. Probes have a resolution of one
+// second, so the duration is rounded up (rounding sub-second durations to 0 would make probes invalid).
+func durationToProbeSeconds(d time.Duration) int32 {
+	seconds := math.Ceil(d.Seconds())
+	if seconds < 1 {
+		return 1
+	}
+	if seconds > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int32(seconds)
 }
 
 func newFalsePointer() *bool {
@@ -79,10 +150,11 @@ func inspectImageRawParseHealthcheck(inspectRaw []byte) (*config.Healthcheck, er
 	var inspectInfo struct {
 		Config struct {
 			Healthcheck struct {
-				Test     []string `json:"Test"`
-				Timeout  *int64   `json:"Timeout"`
-				Interval *int64   `json:"Interval"`
-				Retries  *uint    `json:"Retries"`
+				Test        []string `json:"Test"`
+				Timeout     *int64   `json:"Timeout"`
+				Interval    *int64   `json:"Interval"`
+				Retries     *uint    `json:"Retries"`
+				StartPeriod *int64   `json:"StartPeriod"`
 			} `json:"Healthcheck"`
 		} `json:"Config"`
 	}
@@ -111,6 +183,9 @@ func inspectImageRawParseHealthcheck(inspectRaw []byte) (*config.Healthcheck, er
 	if inspectInfo.Config.Healthcheck.Retries != nil {
 		healthcheck.Retries = *inspectInfo.Config.Healthcheck.Retries
 	}
+	if inspectInfo.Config.Healthcheck.StartPeriod != nil {
+		healthcheck.StartPeriod = time.Duration(*inspectInfo.Config.Healthcheck.StartPeriod)
+	}
 	return healthcheck, nil
 }
 
@@ -208,6 +283,35 @@ func pullImageWithLogging(ctx context.Context, dockerClient *dockerClient.Client
 	return digest, nil
 }
 
+// buildImageWithLogging builds an image and tags it as image, and returns the ID of the image and the raw output of inspecting it.
+func buildImageWithLogging(ctx context.Context, dockerClient *dockerClient.Client, appName, image string, build *config.ServiceBuild) (string, []byte, error) {
+	buildContext, err := docker.ArchiveBuildContext(build.Context, build.Dockerfile)
+	if err != nil {
+		return "", nil, err
+	}
+	defer buildContext.Close()
+	fmt.Printf("app %s: building image %s\n", appName, image)
+	err = docker.BuildImage(ctx, dockerClient, buildContext, dockerTypes.ImageBuildOptions{
+		BuildArgs:  build.Args,
+		CacheFrom:  build.CacheFrom,
+		Dockerfile: build.Dockerfile,
+		Remove:     true,
+		Tags:       []string{image},
+		Target:     build.Target,
+	}, func(step string) {
+		fmt.Printf("app %s: building image %s (%s)\n", appName, image, step)
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	inspect, inspectRaw, err := dockerClient.ImageInspectWithRaw(ctx, image)
+	if err != nil {
+		return "", nil, err
+	}
+	fmt.Printf("app %s: building image %s   @%s\n", appName, image, inspect.ID)
+	return inspect.ID, inspectRaw, nil
+}
+
 func pushImageWithLogging(ctx context.Context, dockerClient *dockerClient.Client, appName, image, bearerToken string) (string, error) {
 	lastLogTime := time.Now().Add(-2 * time.Second)
 	registryAuth, err := docker.EncodeRegistryAuth("unused", bearerToken)
@@ -238,3 +342,15 @@ func contains(arr []string, str string) bool {
 	}
 	return false
 }
+
+// removeDuplicates removes adjacent duplicates from the sorted slice arr, in place.
+func removeDuplicates(arr []string) []string {
+	n := 0
+	for i, a := range arr {
+		if i == 0 || a != arr[n-1] {
+			arr[n] = a
+			n++
+		}
+	}
+	return arr[:n]
+}
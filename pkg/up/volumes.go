@@ -0,0 +1,214 @@
+package up
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+
+	"github.com/jbrekelmans/kube-compose/pkg/config"
+	k8sUtil "github.com/jbrekelmans/kube-compose/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+	k8sError "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	annotationVolumeName = "kube-compose/volume"
+	// The size of a ConfigMap is limited by the maximum size of objects in etcd.
+	maxBindMountSize = 1024 * 1024
+)
+
+func (u *upRunner) initVolumeObjectMeta(objectMeta *metav1.ObjectMeta, name string) {
+	objectMeta.Name = k8sUtil.EncodeName(name) + "-" + u.cfg.EnvironmentID
+	objectMeta.Labels = map[string]string{
+		u.cfg.EnvironmentLabel: u.cfg.EnvironmentID,
+	}
+	objectMeta.Annotations = map[string]string{
+		annotationVolumeName: name,
+	}
+	u.stampObjectMeta(objectMeta)
+}
+
+func (u *upRunner) newPersistentVolumeClaim(volume *config.Volume) (*v1.PersistentVolumeClaim, error) {
+	size, err := resource.ParseQuantity(volume.PersistentVolumeClaim.Size)
+	if err != nil {
+		return nil, err
+	}
+	accessModes := []v1.PersistentVolumeAccessMode{
+		v1.ReadWriteOnce,
+	}
+	if len(volume.PersistentVolumeClaim.AccessModes) > 0 {
+		accessModes = make([]v1.PersistentVolumeAccessMode, len(volume.PersistentVolumeClaim.AccessModes))
+		for i, accessMode := range volume.PersistentVolumeClaim.AccessModes {
+			accessModes[i] = v1.PersistentVolumeAccessMode(accessMode)
+		}
+	}
+	pvc := &v1.PersistentVolumeClaim{
+		Spec: v1.PersistentVolumeClaimSpec{
+			AccessModes: accessModes,
+			Resources: v1.VolumeResourceRequirements{
+				Requests: v1.ResourceList{
+					v1.ResourceStorage: size,
+				},
+			},
+			StorageClassName: volume.PersistentVolumeClaim.StorageClass,
+		},
+	}
+	u.initVolumeObjectMeta(&pvc.ObjectMeta, volume.Name)
+	return pvc, nil
+}
+
+// createPersistentVolumeClaims creates a PersistentVolumeClaim for each named volume that is configured to be persistent in
+// x-kube-compose. Other named volumes are backed by an emptyDir.
+func (u *upRunner) createPersistentVolumeClaims() error {
+	for name, volume := range u.cfg.CanonicalComposeFile.Volumes {
+		if volume.PersistentVolumeClaim == nil {
+			continue
+		}
+		pvc, err := u.newPersistentVolumeClaim(volume)
+		if err != nil {
+			return err
+		}
+		_, err = u.k8sPersistentVolumeClaimClient.Create(u.ctx, pvc, metav1.CreateOptions{})
+		if k8sError.IsAlreadyExists(err) {
+			fmt.Printf("volume %s: persistent volume claim %s already exists\n", name, pvc.ObjectMeta.Name)
+		} else if err != nil {
+			return err
+		} else {
+			fmt.Printf("volume %s: created persistent volume claim %s\n", name, pvc.ObjectMeta.Name)
+		}
+	}
+	return nil
+}
+
+// newPodVolumes returns the volumes and volume mounts of the pod of app. Read-only bind mounts are shipped as ConfigMaps, which are
+// also returned.
+func (u *upRunner) newPodVolumes(app *app) ([]v1.Volume, []v1.VolumeMount, []*v1.ConfigMap, error) {
+	dcService := u.cfg.CanonicalComposeFile.Services[app.name]
+	n := len(dcService.Volumes)
+	if n == 0 {
+		return nil, nil, nil, nil
+	}
+	volumes := make([]v1.Volume, n)
+	volumeMounts := make([]v1.VolumeMount, n)
+	configMaps := []*v1.ConfigMap{}
+	for i, serviceVolume := range dcService.Volumes {
+		volumes[i].Name = fmt.Sprintf("volume%d", i)
+		volumeMounts[i] = v1.VolumeMount{
+			MountPath: serviceVolume.Target,
+			Name:      volumes[i].Name,
+			ReadOnly:  serviceVolume.ReadOnly,
+		}
+		switch serviceVolume.Type {
+		case config.VolumeTypeTmpfs:
+			volumes[i].EmptyDir = &v1.EmptyDirVolumeSource{
+				Medium: v1.StorageMediumMemory,
+			}
+		case config.VolumeTypeVolume:
+			volume := u.cfg.CanonicalComposeFile.Volumes[serviceVolume.Source]
+			if volume != nil && volume.PersistentVolumeClaim != nil {
+				pvc := &v1.PersistentVolumeClaim{}
+				u.initVolumeObjectMeta(&pvc.ObjectMeta, volume.Name)
+				volumes[i].PersistentVolumeClaim = &v1.PersistentVolumeClaimVolumeSource{
+					ClaimName: pvc.ObjectMeta.Name,
+					ReadOnly:  serviceVolume.ReadOnly,
+				}
+			} else {
+				// Anonymous volumes and named volumes without configuration.
+				volumes[i].EmptyDir = &v1.EmptyDirVolumeSource{}
+			}
+		case config.VolumeTypeBind:
+			configMap, subPath, err := u.newBindMountConfigMap(app, i, &serviceVolume)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			configMaps = append(configMaps, configMap)
+			volumes[i].ConfigMap = &v1.ConfigMapVolumeSource{
+				LocalObjectReference: v1.LocalObjectReference{
+					Name: configMap.ObjectMeta.Name,
+				},
+			}
+			volumeMounts[i].SubPath = subPath
+		default:
+			return nil, nil, nil, fmt.Errorf("app %s: volume type %s is not supported", app.name, serviceVolume.Type)
+		}
+	}
+	return volumes, volumeMounts, configMaps, nil
+}
+
+// newBindMountConfigMap returns a ConfigMap with the contents of the source of a bind mount, which must be a small file or a small
+// directory containing only files. Also returns the sub path to mount (if the source is a file).
+func (u *upRunner) newBindMountConfigMap(app *app, index int, serviceVolume *config.ServiceVolume) (*v1.ConfigMap, string, error) {
+	info, err := os.Stat(serviceVolume.Source)
+	if err != nil {
+		return nil, "", err
+	}
+	configMap := &v1.ConfigMap{
+		Data:       map[string]string{},
+		BinaryData: map[string][]byte{},
+	}
+	size := 0
+	addFile := func(key, path string) error {
+		if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+			return fmt.Errorf("app %s: bind mount %s cannot be shipped as a ConfigMap because of file %s: %s", app.name, serviceVolume.Source, path, errs[0])
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		size += len(data)
+		if size > maxBindMountSize {
+			return fmt.Errorf("app %s: bind mount %s is too large, bind mounts must be at most %d bytes", app.name, serviceVolume.Source, maxBindMountSize)
+		}
+		if utf8.Valid(data) {
+			configMap.Data[key] = string(data)
+		} else {
+			configMap.BinaryData[key] = data
+		}
+		return nil
+	}
+	subPath := ""
+	if info.IsDir() {
+		fileInfos, err := ioutil.ReadDir(serviceVolume.Source)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, fileInfo := range fileInfos {
+			path := filepath.Join(serviceVolume.Source, fileInfo.Name())
+			if !fileInfo.Mode().IsRegular() {
+				return nil, "", fmt.Errorf("app %s: bind mount %s contains %s, but only directories containing only files are supported", app.name, serviceVolume.Source, path)
+			}
+			err = addFile(fileInfo.Name(), path)
+			if err != nil {
+				return nil, "", err
+			}
+		}
+	} else {
+		subPath = filepath.Base(serviceVolume.Source)
+		err = addFile(subPath, serviceVolume.Source)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	u.initResourceObjectMeta(&configMap.ObjectMeta, app.nameEncoded, app.name)
+	configMap.ObjectMeta.Name = fmt.Sprintf("%s-bind%d-%s", app.nameEncoded, index, u.cfg.EnvironmentID)
+	return configMap, subPath, nil
+}
+
+func (u *upRunner) createConfigMaps(app *app, configMaps []*v1.ConfigMap) error {
+	for _, configMap := range configMaps {
+		_, err := u.k8sConfigMapClient.Create(u.ctx, configMap, metav1.CreateOptions{})
+		if k8sError.IsAlreadyExists(err) {
+			fmt.Printf("app %s: config map %s already exists\n", app.name, configMap.ObjectMeta.Name)
+		} else if err != nil {
+			return err
+		} else {
+			fmt.Printf("app %s: created config map %s\n", app.name, configMap.ObjectMeta.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package up
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	k8sError "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// The delay before re-establishing a watch after a transient error.
+const watchRetryDelay = time.Second
+
+type listFunc func(ctx context.Context, listOptions metav1.ListOptions) (runtime.Object, error)
+
+type watchFunc func(ctx context.Context, listOptions metav1.ListOptions) (watch.Interface, error)
+
+// watcher delivers the events of a watch, and re-establishes the watch when the API server closes it (which API servers do routinely).
+// The watch is resumed from the resource version of the last event. If that resource version has expired the objects are listed
+// again, and the difference with the objects known from earlier events is delivered as ADDED, MODIFIED and DELETED events.
+type watcher struct {
+	ctx             context.Context
+	known           map[string]runtime.Object // the objects that were listed or delivered, by namespace and name.
+	list            listFunc
+	listOptions     metav1.ListOptions
+	pending         []watch.Event
+	resourceVersion string
+	watch           watch.Interface
+	watchFunc       watchFunc
+}
+
+// newWatcher returns a watcher for the objects selected by listOptions, starting at the resource version of initialList (the result of
+// listing the objects with listOptions).
+func newWatcher(ctx context.Context, listOptions metav1.ListOptions, initialList runtime.Object, list listFunc,
+	watchFunc watchFunc) (*watcher, error) {
+	w := &watcher{
+		ctx:         ctx,
+		known:       map[string]runtime.Object{},
+		list:        list,
+		listOptions: listOptions,
+		watchFunc:   watchFunc,
+	}
+	objects, resourceVersion, err := extractList(initialList)
+	if err != nil {
+		return nil, err
+	}
+	for _, object := range objects {
+		w.track(watch.Event{
+			Type:   watch.Added,
+			Object: object,
+		})
+	}
+	w.resourceVersion = resourceVersion
+	return w, nil
+}
+
+func extractList(listObject runtime.Object) ([]runtime.Object, string, error) {
+	listMeta, err := meta.ListAccessor(listObject)
+	if err != nil {
+		return nil, "", err
+	}
+	objects, err := meta.ExtractList(listObject)
+	if err != nil {
+		return nil, "", err
+	}
+	return objects, listMeta.GetResourceVersion(), nil
+}
+
+func watchKey(object runtime.Object) (string, bool) {
+	objectMeta, err := meta.Accessor(object)
+	if err != nil {
+		return "", false
+	}
+	return objectMeta.GetNamespace() + "/" + objectMeta.GetName(), true
+}
+
+// track updates the known objects with event, which is delivered to the caller of next.
+func (w *watcher) track(event watch.Event) {
+	key, ok := watchKey(event.Object)
+	if !ok {
+		return
+	}
+	if event.Type == watch.Deleted {
+		delete(w.known, key)
+	} else {
+		w.known[key] = event.Object
+	}
+}
+
+// isResourceVersionExpired returns true if err indicates that a resource version is too old to watch from (410 Gone).
+func isResourceVersionExpired(err error) bool {
+	if status, ok := err.(k8sError.APIStatus); ok {
+		return status.Status().Code == http.StatusGone
+	}
+	return false
+}
+
+func isWatchErrorRetryable(err error) bool {
+	return k8sError.IsServerTimeout(err) ||
+		k8sError.IsTimeout(err) ||
+		k8sError.IsTooManyRequests(err) ||
+		k8sError.IsInternalError(err) ||
+		k8sError.IsUnexpectedServerError(err)
+}
+
+// relist lists the objects again, and queues the events that bring the known objects up to date. Objects that are no longer listed
+// were deleted while the watch was not established.
+func (w *watcher) relist() error {
+	listOptions := w.listOptions
+	listOptions.ResourceVersion = ""
+	listOptions.Watch = false
+	listObject, err := w.list(w.ctx, listOptions)
+	if err != nil {
+		return err
+	}
+	objects, resourceVersion, err := extractList(listObject)
+	if err != nil {
+		return err
+	}
+	listed := map[string]bool{}
+	for _, object := range objects {
+		key, ok := watchKey(object)
+		if !ok {
+			continue
+		}
+		listed[key] = true
+		eventType := watch.Modified
+		if _, ok := w.known[key]; !ok {
+			eventType = watch.Added
+		}
+		w.pending = append(w.pending, watch.Event{
+			Type:   eventType,
+			Object: object,
+		})
+	}
+	keys := make([]string, 0, len(w.known))
+	for key := range w.known {
+		if !listed[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		w.pending = append(w.pending, watch.Event{
+			Type:   watch.Deleted,
+			Object: w.known[key],
+		})
+	}
+	w.resourceVersion = resourceVersion
+	return nil
+}
+
+func (w *watcher) sleep() error {
+	select {
+	case <-w.ctx.Done():
+		return w.ctx.Err()
+	case <-time.After(watchRetryDelay):
+		return nil
+	}
+}
+
+// handleError re-establishes the watch after err, or returns err if it is not recoverable.
+func (w *watcher) handleError(err error) error {
+	w.stop()
+	if isResourceVersionExpired(err) {
+		return w.relist()
+	}
+	if isWatchErrorRetryable(err) {
+		return w.sleep()
+	}
+	return err
+}
+
+// next returns the next event, or an error if ctx is done or the watch failed in a way that cannot be recovered from.
+func (w *watcher) next() (watch.Event, error) {
+	for {
+		if len(w.pending) > 0 {
+			event := w.pending[0]
+			w.pending = w.pending[1:]
+			w.track(event)
+			return event, nil
+		}
+		if err := w.ctx.Err(); err != nil {
+			return watch.Event{}, err
+		}
+		if w.watch == nil {
+			listOptions := w.listOptions
+			listOptions.ResourceVersion = w.resourceVersion
+			listOptions.Watch = true
+			watchInterface, err := w.watchFunc(w.ctx, listOptions)
+			if err != nil {
+				if err = w.handleError(err); err != nil {
+					return watch.Event{}, err
+				}
+				continue
+			}
+			w.watch = watchInterface
+		}
+		var event watch.Event
+		var ok bool
+		select {
+		case <-w.ctx.Done():
+			return watch.Event{}, w.ctx.Err()
+		case event, ok = <-w.watch.ResultChan():
+		}
+		if !ok {
+			// The API server closed the watch, resume from the last resource version.
+			w.stop()
+			continue
+		}
+		if event.Type == watch.Error {
+			err := k8sError.FromObject(event.Object)
+			if err = w.handleError(err); err != nil {
+				return watch.Event{}, fmt.Errorf("got unexpected error event from channel: %v", err)
+			}
+			continue
+		}
+		if objectMeta, err := meta.Accessor(event.Object); err == nil {
+			w.resourceVersion = objectMeta.GetResourceVersion()
+		}
+		w.track(event)
+		return event, nil
+	}
+}
+
+func (w *watcher) stop() {
+	if w.watch != nil {
+		w.watch.Stop()
+		w.watch = nil
+	}
+}
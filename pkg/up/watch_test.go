@@ -0,0 +1,227 @@
+package up
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	k8sError "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+type testWatchResult struct {
+	watch watch.Interface
+	err   error
+}
+
+// testWatchServer fakes the list and watch calls of a watcher, and records the list options of the calls.
+type testWatchServer struct {
+	listOptions  []metav1.ListOptions
+	podList      *v1.PodList
+	watchOptions []metav1.ListOptions
+	watches      []testWatchResult
+}
+
+func (s *testWatchServer) list(ctx context.Context, listOptions metav1.ListOptions) (runtime.Object, error) {
+	s.listOptions = append(s.listOptions, listOptions)
+	if s.podList == nil {
+		return nil, fmt.Errorf("unexpected list")
+	}
+	return s.podList, nil
+}
+
+func (s *testWatchServer) watch(ctx context.Context, listOptions metav1.ListOptions) (watch.Interface, error) {
+	s.watchOptions = append(s.watchOptions, listOptions)
+	if len(s.watches) == 0 {
+		return nil, fmt.Errorf("unexpected watch")
+	}
+	result := s.watches[0]
+	s.watches = s.watches[1:]
+	return result.watch, result.err
+}
+
+// newWatcher returns a watcher that starts at resource version 1, at which initialPods existed.
+func (s *testWatchServer) newWatcher(t *testing.T, initialPods ...*v1.Pod) *watcher {
+	w, err := newWatcher(context.Background(), metav1.ListOptions{}, newTestWatchPodList("1", initialPods...), s.list, s.watch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func newTestWatchPodList(resourceVersion string, pods ...*v1.Pod) *v1.PodList {
+	podList := &v1.PodList{}
+	podList.ListMeta.ResourceVersion = resourceVersion
+	for _, pod := range pods {
+		podList.Items = append(podList.Items, *pod)
+	}
+	return podList
+}
+
+func newTestWatchPod(name, resourceVersion string) *v1.Pod {
+	pod := &v1.Pod{}
+	pod.ObjectMeta.Name = name
+	pod.ObjectMeta.ResourceVersion = resourceVersion
+	return pod
+}
+
+// newTestFakeWatch returns a fake watch that delivers events and is then closed by the API server.
+func newTestFakeWatch(events ...watch.Event) *watch.FakeWatcher {
+	fakeWatch := watch.NewFakeWithChanSize(len(events), false)
+	for _, event := range events {
+		fakeWatch.Action(event.Type, event.Object)
+	}
+	fakeWatch.Stop()
+	return fakeWatch
+}
+
+func expectWatchEvent(t *testing.T, w *watcher, eventType watch.EventType, name string) {
+	event, err := w.next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod, ok := event.Object.(*v1.Pod)
+	if event.Type != eventType || !ok || pod.ObjectMeta.Name != name {
+		t.Fatalf("expected %s event of pod %s, but got %s event %+v", eventType, name, event.Type, event.Object)
+	}
+}
+
+func expectResourceVersions(t *testing.T, listOptions []metav1.ListOptions, expected ...string) {
+	if len(listOptions) != len(expected) {
+		t.Fatalf("expected %d calls, but got %d", len(expected), len(listOptions))
+	}
+	for i, resourceVersion := range expected {
+		if listOptions[i].ResourceVersion != resourceVersion {
+			t.Errorf("call %d: expected resource version %#v, but got %#v", i+1, resourceVersion, listOptions[i].ResourceVersion)
+		}
+	}
+}
+
+func TestWatcherResumesClosedWatch(t *testing.T) {
+	s := &testWatchServer{
+		watches: []testWatchResult{
+			{watch: newTestFakeWatch(watch.Event{Type: watch.Added, Object: newTestWatchPod("a", "2")})},
+			{watch: newTestFakeWatch(watch.Event{Type: watch.Modified, Object: newTestWatchPod("a", "3")})},
+		},
+	}
+	w := s.newWatcher(t)
+	defer w.stop()
+	expectWatchEvent(t, w, watch.Added, "a")
+	expectWatchEvent(t, w, watch.Modified, "a")
+	expectResourceVersions(t, s.watchOptions, "1", "2")
+	if len(s.listOptions) != 0 {
+		t.Fail()
+	}
+}
+
+func TestWatcherRelistsExpiredResourceVersion(t *testing.T) {
+	testCases := []struct {
+		name   string
+		result testWatchResult
+	}{
+		{
+			name: "error event",
+			result: testWatchResult{
+				watch: newTestFakeWatch(watch.Event{
+					Type: watch.Error,
+					Object: &metav1.Status{
+						Code:   http.StatusGone,
+						Reason: metav1.StatusReasonExpired,
+						Status: metav1.StatusFailure,
+					},
+				}),
+			},
+		},
+		{
+			name: "error",
+			result: testWatchResult{
+				err: k8sError.NewResourceExpired("too old resource version"),
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		s := &testWatchServer{
+			podList: newTestWatchPodList("5", newTestWatchPod("a", "4"), newTestWatchPod("b", "5")),
+			watches: []testWatchResult{
+				testCase.result,
+				{watch: newTestFakeWatch(watch.Event{Type: watch.Added, Object: newTestWatchPod("c", "6")})},
+			},
+		}
+		// d was deleted while the watch was not established.
+		w := s.newWatcher(t, newTestWatchPod("a", "1"), newTestWatchPod("d", "1"))
+		expectWatchEvent(t, w, watch.Modified, "a")
+		expectWatchEvent(t, w, watch.Added, "b")
+		expectWatchEvent(t, w, watch.Deleted, "d")
+		expectWatchEvent(t, w, watch.Added, "c")
+		w.stop()
+		expectResourceVersions(t, s.watchOptions, "1", "5")
+		expectResourceVersions(t, s.listOptions, "")
+		if s.listOptions[0].Watch {
+			t.Errorf("%s: expected list without watch", testCase.name)
+		}
+	}
+}
+
+func TestWatcherRetriesTransientError(t *testing.T) {
+	s := &testWatchServer{
+		watches: []testWatchResult{
+			{err: k8sError.NewTooManyRequests("slow down", 1)},
+			{watch: newTestFakeWatch(watch.Event{Type: watch.Added, Object: newTestWatchPod("a", "2")})},
+		},
+	}
+	w := s.newWatcher(t)
+	defer w.stop()
+	expectWatchEvent(t, w, watch.Added, "a")
+	expectResourceVersions(t, s.watchOptions, "1", "1")
+}
+
+func TestWatcherReturnsPermanentError(t *testing.T) {
+	s := &testWatchServer{
+		watches: []testWatchResult{
+			{err: k8sError.NewForbidden(schema.GroupResource{Resource: "pods"}, "", fmt.Errorf("denied"))},
+		},
+	}
+	w := s.newWatcher(t)
+	defer w.stop()
+	_, err := w.next()
+	if !k8sError.IsForbidden(err) {
+		t.Fatalf("expected forbidden error, but got %v", err)
+	}
+}
+
+func TestWatcherCancelled(t *testing.T) {
+	s := &testWatchServer{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w, err := newWatcher(ctx, metav1.ListOptions{}, newTestWatchPodList("1"), s.list, s.watch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = w.next()
+	if err != context.Canceled || len(s.watchOptions) != 0 {
+		t.Fail()
+	}
+}
+
+func TestWatcherRelistAfterDeleteEvent(t *testing.T) {
+	s := &testWatchServer{
+		podList: newTestWatchPodList("4", newTestWatchPod("b", "3")),
+		watches: []testWatchResult{
+			{watch: newTestFakeWatch(watch.Event{Type: watch.Deleted, Object: newTestWatchPod("a", "2")})},
+			{err: k8sError.NewResourceExpired("too old resource version")},
+		},
+	}
+	w := s.newWatcher(t, newTestWatchPod("a", "1"), newTestWatchPod("b", "1"))
+	defer w.stop()
+	expectWatchEvent(t, w, watch.Deleted, "a")
+	// a must not be deleted again, because its deletion was already delivered.
+	expectWatchEvent(t, w, watch.Modified, "b")
+	if len(w.pending) != 0 {
+		t.Fatalf("expected no more events, but got %+v", w.pending)
+	}
+}
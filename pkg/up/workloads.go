@@ -0,0 +1,120 @@
+package up
+
+import (
+	"fmt"
+
+	"github.com/jbrekelmans/kube-compose/pkg/config"
+	appsV1 "k8s.io/api/apps/v1"
+	batchV1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// workload is a Job, Deployment or StatefulSet that manages the pods of an app, see config.Service.Kind.
+type workload struct {
+	groupVersion schema.GroupVersion
+	kind         string
+	objectMeta   *metav1.ObjectMeta
+	object       runtime.Object
+}
+
+// newPodTemplateSpec returns the template of the pods of the workload of app, where pod is the pod of app (see newPod). The pods of
+// workloads are named by their controller.
+func (u *upRunner) newPodTemplateSpec(app *app, pod *v1.Pod) v1.PodTemplateSpec {
+	template := v1.PodTemplateSpec{
+		ObjectMeta: *pod.ObjectMeta.DeepCopy(),
+		Spec:       *pod.Spec.DeepCopy(),
+	}
+	u.initResourceObjectMeta(&template.ObjectMeta, app.nameEncoded, app.name)
+	template.ObjectMeta.Name = ""
+	// Pods of workloads are owned by their controller, which is owned by the anchor.
+	template.ObjectMeta.OwnerReferences = nil
+	return template
+}
+
+func (u *upRunner) newWorkloadSelector(app *app) *metav1.LabelSelector {
+	return &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			"app":                  app.nameEncoded,
+			u.cfg.EnvironmentLabel: u.cfg.EnvironmentID,
+		},
+	}
+}
+
+// newWorkload returns the workload of app, whose pods are created from pod (see newPod). Returns nil if app runs bare pods.
+func (u *upRunner) newWorkload(app *app, pod *v1.Pod) *workload {
+	replicas := int32(len(app.replicas))
+	w := &workload{}
+	switch u.cfg.CanonicalComposeFile.Services[app.name].Kind {
+	case config.KindJob:
+		job := &batchV1.Job{
+			Spec: batchV1.JobSpec{
+				Completions: &replicas,
+				Parallelism: &replicas,
+				Template:    u.newPodTemplateSpec(app, pod),
+			},
+		}
+		if pod.Spec.RestartPolicy == v1.RestartPolicyNever {
+			// Like bare pods, pods that fail are not retried.
+			backoffLimit := int32(0)
+			job.Spec.BackoffLimit = &backoffLimit
+		}
+		w.groupVersion = batchV1.SchemeGroupVersion
+		w.kind = "Job"
+		w.objectMeta = &job.ObjectMeta
+		w.object = job
+	case config.KindDeployment:
+		deployment := &appsV1.Deployment{
+			Spec: appsV1.DeploymentSpec{
+				Replicas: &replicas,
+				Selector: u.newWorkloadSelector(app),
+				Template: u.newPodTemplateSpec(app, pod),
+			},
+		}
+		w.groupVersion = appsV1.SchemeGroupVersion
+		w.kind = "Deployment"
+		w.objectMeta = &deployment.ObjectMeta
+		w.object = deployment
+	case config.KindStatefulSet:
+		statefulSet := &appsV1.StatefulSet{
+			Spec: appsV1.StatefulSetSpec{
+				// Replicas are started at the same time, like the replicas of other kinds.
+				PodManagementPolicy: appsV1.ParallelPodManagement,
+				Replicas:            &replicas,
+				Selector:            u.newWorkloadSelector(app),
+				ServiceName:         u.subdomain(), // the governing service must be headless, see needsHeadlessService.
+				Template:            u.newPodTemplateSpec(app, pod),
+			},
+		}
+		w.groupVersion = appsV1.SchemeGroupVersion
+		w.kind = "StatefulSet"
+		w.objectMeta = &statefulSet.ObjectMeta
+		w.object = statefulSet
+	default:
+		return nil
+	}
+	u.initResourceObjectMeta(w.objectMeta, app.nameEncoded, app.name)
+	return w
+}
+
+// createWorkload creates the workload w of app, where reason explains why the workload is created.
+func (u *upRunner) createWorkload(app *app, w *workload, reason string) error {
+	var err error
+	switch object := w.object.(type) {
+	case *batchV1.Job:
+		_, err = u.k8sJobClient.Create(u.ctx, object, metav1.CreateOptions{})
+	case *appsV1.Deployment:
+		_, err = u.k8sDeploymentClient.Create(u.ctx, object, metav1.CreateOptions{})
+	case *appsV1.StatefulSet:
+		_, err = u.k8sStatefulSetClient.Create(u.ctx, object, metav1.CreateOptions{})
+	default:
+		return fmt.Errorf("app %s: unsupported workload kind %s", app.name, w.kind)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("app %s: created %s %s because %s\n", app.name, w.kind, w.objectMeta.Name, reason)
+	return nil
+}
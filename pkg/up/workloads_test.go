@@ -0,0 +1,123 @@
+package up
+
+import (
+	"testing"
+
+	appsV1 "k8s.io/api/apps/v1"
+	batchV1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+const testWorkloadsComposeFile = `version: '2.4'
+services:
+  bare:
+    image: bare
+  job:
+    image: job
+    scale: 2
+    x-kube-compose:
+      kind: job
+  retry:
+    image: retry
+    restart: on-failure
+    x-kube-compose:
+      kind: job
+  deployment:
+    image: deployment
+    restart: always
+    scale: 3
+    x-kube-compose:
+      kind: deployment
+  statefulset:
+    image: statefulset
+    restart: unless-stopped
+    x-kube-compose:
+      kind: statefulset
+`
+
+// newTestWorkload returns the workload of the app named name, and fails the test if its kind is not kind.
+func newTestWorkload(t *testing.T, u *upRunner, name, kind string) *workload {
+	app := u.apps[name]
+	pod := &v1.Pod{}
+	u.initResourceObjectMeta(&pod.ObjectMeta, app.nameEncoded, app.name)
+	pod.Spec.RestartPolicy = newRestartPolicy(u.cfg.CanonicalComposeFile.Services[name])
+	w := u.newWorkload(app, pod)
+	if w == nil || w.kind != kind {
+		t.Fatalf("expected the workload of %s to be a %s, but got %+v", name, kind, w)
+	}
+	if w.objectMeta.Name != app.nameEncoded+"-"+testEnvironmentID {
+		t.Errorf("unexpected name %s", w.objectMeta.Name)
+	}
+	return w
+}
+
+func checkPodTemplateSpec(t *testing.T, template *v1.PodTemplateSpec, restartPolicy v1.RestartPolicy) {
+	if len(template.ObjectMeta.Name) > 0 {
+		t.Errorf("expected the pod template to have no name, but got %s", template.ObjectMeta.Name)
+	}
+	if template.Spec.RestartPolicy != restartPolicy {
+		t.Errorf("expected restart policy %s, but got %s", restartPolicy, template.Spec.RestartPolicy)
+	}
+}
+
+func TestNewWorkloadBarePod(t *testing.T) {
+	u := newTestUpRunner(t, testWorkloadsComposeFile)
+	if w := u.newWorkload(u.apps["bare"], &v1.Pod{}); w != nil {
+		t.Errorf("expected no workload, but got a %s", w.kind)
+	}
+}
+
+func TestNewWorkloadJob(t *testing.T) {
+	u := newTestUpRunner(t, testWorkloadsComposeFile)
+	job := newTestWorkload(t, u, "job", "Job").object.(*batchV1.Job)
+	if *job.Spec.Completions != 2 || *job.Spec.Parallelism != 2 {
+		t.Errorf("expected 2 completions and parallelism 2, but got %d and %d", *job.Spec.Completions, *job.Spec.Parallelism)
+	}
+	if job.Spec.BackoffLimit == nil || *job.Spec.BackoffLimit != 0 {
+		t.Errorf("expected a backoff limit of 0, but got %v", job.Spec.BackoffLimit)
+	}
+	checkPodTemplateSpec(t, &job.Spec.Template, v1.RestartPolicyNever)
+}
+
+func TestNewWorkloadJobOnFailure(t *testing.T) {
+	u := newTestUpRunner(t, testWorkloadsComposeFile)
+	job := newTestWorkload(t, u, "retry", "Job").object.(*batchV1.Job)
+	if job.Spec.BackoffLimit != nil {
+		t.Errorf("expected the default backoff limit, but got %d", *job.Spec.BackoffLimit)
+	}
+	checkPodTemplateSpec(t, &job.Spec.Template, v1.RestartPolicyOnFailure)
+}
+
+func TestNewWorkloadDeployment(t *testing.T) {
+	u := newTestUpRunner(t, testWorkloadsComposeFile)
+	deployment := newTestWorkload(t, u, "deployment", "Deployment").object.(*appsV1.Deployment)
+	if *deployment.Spec.Replicas != 3 {
+		t.Errorf("expected 3 replicas, but got %d", *deployment.Spec.Replicas)
+	}
+	for key, value := range deployment.Spec.Selector.MatchLabels {
+		if deployment.Spec.Template.ObjectMeta.Labels[key] != value {
+			t.Errorf("selector label %s=%s does not select the pod template", key, value)
+		}
+	}
+	checkPodTemplateSpec(t, &deployment.Spec.Template, v1.RestartPolicyAlways)
+}
+
+func TestNewWorkloadStatefulSet(t *testing.T) {
+	u := newTestUpRunner(t, testWorkloadsComposeFile)
+	statefulSet := newTestWorkload(t, u, "statefulset", "StatefulSet").object.(*appsV1.StatefulSet)
+	if *statefulSet.Spec.Replicas != 1 {
+		t.Errorf("expected 1 replica, but got %d", *statefulSet.Spec.Replicas)
+	}
+	if statefulSet.Spec.ServiceName != u.subdomain() {
+		t.Errorf("expected the governing service %s, but got %s", u.subdomain(), statefulSet.Spec.ServiceName)
+	}
+	if statefulSet.Spec.PodManagementPolicy != appsV1.ParallelPodManagement {
+		t.Errorf("expected parallel pod management, but got %s", statefulSet.Spec.PodManagementPolicy)
+	}
+	for key, value := range statefulSet.Spec.Selector.MatchLabels {
+		if statefulSet.Spec.Template.ObjectMeta.Labels[key] != value {
+			t.Errorf("selector label %s=%s does not select the pod template", key, value)
+		}
+	}
+	checkPodTemplateSpec(t, &statefulSet.Spec.Template, v1.RestartPolicyAlways)
+}